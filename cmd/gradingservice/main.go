@@ -11,6 +11,10 @@ import (
 )
 
 func main() {
+	// 从磁盘加载成绩数据，文件不存在时使用内置的模拟数据
+	if err := grades.LoadStudents("./grades.json"); err != nil {
+		stlog.Fatalln(err)
+	}
 
 	// 设置服务主机名和端口
 	host, port := "localhost", "6000"
@@ -39,14 +43,15 @@ func main() {
 	if err != nil {
 		stlog.Fatalln(err)
 	}
-	if logProvider, err := registry.GetProvider(registry.LogService); err == nil {
-		fmt.Printf("log service found at %s\n", logProvider)
-		log.SetClientLogger(logProvider, r.ServiceName)
-	}
+	// 日志服务已上线时立即使用，之后上线或切换实例时自动重新配置
+	log.WatchProvider(r.ServiceName)
 
 	// 阻塞等待上下文被取消（服务关闭信号）
 	<-ctx.Done()
 
+	// 发送完队列中剩余的日志
+	log.CloseClient()
+
 	// 输出服务关闭消息
 	fmt.Println("shutting down log service")
 }
@@ -13,7 +13,9 @@ import (
 // 日志服务负责接收其他服务发送的日志信息并将其写入文件
 func main() {
 	// 初始化日志系统，指定日志文件路径
-	log.Run("./distributed.log")
+	if err := log.Run("./distributed.log", log.DefaultMaxBytes, log.DefaultMaxBackups); err != nil {
+		stlog.Fatalln(err)
+	}
 
 	// 设置服务主机名和端口
 	host, port := "localhost", "4000"
@@ -36,6 +38,8 @@ func main() {
 		host,
 		port,
 		log.RegisterHandlers, // 注册HTTP路由处理函数
+		// 关闭时刷新缓冲区，保证正常关闭时不丢失日志
+		service.WithShutdownHook(log.Shutdown),
 	)
 
 	// 如果启动过程中出现错误，记录并退出
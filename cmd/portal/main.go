@@ -35,10 +35,9 @@ func main() {
 	if err != nil {
 		stlog.Fatal(err)
 	}
-	//为客户端设定logger
-	if logProvider, err := registry.GetProvider(registry.LogService); err == nil {
-		log.SetClientLogger(logProvider, r.ServiceName)
-	}
+	//为客户端设定logger，日志服务晚于门户上线时也能自动接入
+	log.WatchProvider(r.ServiceName)
 	<-ctx.Done()
+	log.CloseClient()
 	fmt.Println("Shutting down portal")
 }
@@ -3,76 +3,121 @@ package main
 import (
 	"My_mimiDistributed/registry"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 )
 
+// stateFile 是注册表持久化文件的路径
+const stateFile = "./registry.json"
+
 // main函数是注册中心服务的入口点
 // 注册中心是整个微服务架构的核心组件，负责服务发现和注册
 // 业务流程:
-// 1. 设置HTTP处理函数，用于服务注册、注销和查询
-// 2. 启动HTTP服务器监听请求
-// 3. 等待服务终止
+// 1. 绑定注册中心端口
+// 2. 运行注册中心，直到收到SIGINT或SIGTERM
+// 3. 等待最后一次持久化完成后退出
 func main() {
+	// 等待SIGINT或SIGTERM，在没有交互式stdin的环境中同样可以优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", registry.ServicePort)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// 打印提示信息，表示服务已启动
+	fmt.Println(" Registry service started. Press Ctrl+C to stop")
+
+	if err := run(ctx, ln, stateFile); err != nil {
+		log.Println(err)
+	}
+}
+
+// run 在ln上运行注册中心，ctx取消或HTTP服务器出错时关闭
+// 所有后台goroutine(包括最后一次持久化)结束后才返回
+// 业务流程:
+// 1. 从磁盘恢复注册表，设置HTTP处理函数
+// 2. 启动持久化、心跳检查和HTTP服务器
+// 3. 关闭时停止HTTP服务器，等待最后一次写入状态文件
+// 参数:
+// - ctx: 控制注册中心生命周期的上下文
+// - ln: 已绑定端口的监听器
+// - path: 状态文件路径
+// 返回:
+// - error: HTTP服务器异常停止的原因，正常关闭时为nil
+func run(ctx context.Context, ln net.Listener, path string) error {
+	// 从磁盘恢复上次运行时的注册表，只恢复仍然存活的服务
+	if err := registry.Load(path); err != nil {
+		log.Println(err)
+	}
+
 	// 创建HTTP多路复用器
 	// 用于将不同路径的请求路由到相应的处理函数
 	// registry.RegistryService实现了ServeHTTP方法，可处理/services路径的请求
-	http.Handle("/services", &registry.RegistryService{})
+	mux := http.NewServeMux()
+	mux.Handle("/services", &registry.RegistryService{})
+
+	// 管理接口，展示每个服务的依赖满足情况
+	mux.Handle("/admin/status", &registry.AdminStatusHandler{})
 
 	// 创建上下文用于控制服务生命周期
-	// 当服务需要关闭时，可以取消这个上下文
-	ctx, cancel := context.WithCancel(context.Background())
+	// HTTP服务器出错时同样取消，使其他goroutine一起结束
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 注册中心关闭时，中止进行中的依赖推送
+	registry.SetContext(ctx)
 
 	// 定义等待组，用于等待所有goroutine完成
 	// 这确保服务在关闭前完成所有必要的清理工作
 	var wg sync.WaitGroup
 
-	// 添加一个等待任务
+	// 定期将注册表写入磁盘，注册中心重启后各服务无需重新注册
+	// 关闭时等待最后一次写入完成后再退出，避免丢失或写坏状态文件
 	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		registry.RunPersistence(ctx, path, registry.DefaultPersistInterval)
+	}()
+
+	// 启动心跳检查，剔除崩溃后未能注销的服务
+	go registry.RunHeartbeat(ctx, registry.DefaultHeartbeatInterval,
+		registry.DefaultHeartbeatThreshold)
 
 	// 启动一个goroutine运行HTTP服务器
 	// 使用goroutine避免阻塞主流程
+	srv := &http.Server{Handler: mux}
+	var serveErr error
+	wg.Add(1)
 	go func() {
-		// 启动HTTP服务器在8000端口监听
+		defer wg.Done()
 		// 服务发现和注册的所有API都通过这个端口提供
-		log.Println(http.ListenAndServe(":3000", nil))
+		if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			serveErr = err
+		}
 
-		// 当服务器关闭时，取消上下文
+		// 当服务器停止时，取消上下文
 		// 这会通知所有使用此上下文的goroutine结束工作
 		cancel()
-
-		// 标记等待任务完成
-		wg.Done()
-	}()
-
-	// 启动一个goroutine监听用户输入，实现优雅关闭
-	// 这提供了一种通过控制台手动关闭注册中心的方式
-	go func() {
-		// 等待上下文结束信号
-		// 当另一个goroutine调用cancel()时，这里会收到信号
-		<-ctx.Done()
-
-		// 打印提示信息，表示服务即将关闭
-		fmt.Println("Registry service shutting down")
-
-		// 标记等待任务完成
-		wg.Done()
 	}()
 
-	// 打印提示信息，表示服务已启动
-	fmt.Println(" Registry service started. Press any key to stop")
-
-	// 等待用户输入，提供手动关闭服务的方式
-	var s string
-	fmt.Scanln(&s)
-
-	// 用户输入后，取消上下文，通知所有goroutine
-	// 这是优雅关闭的第一步，确保相关资源能够正确释放
-	cancel()
+	// 等待上下文结束信号，随后停止HTTP服务器
+	<-ctx.Done()
+	fmt.Println("Registry service shutting down")
+	if err := srv.Shutdown(context.Background()); err != nil {
+		log.Println(err)
+	}
 
 	// 等待所有goroutine完成，确保优雅关闭
 	// 这是微服务设计中的最佳实践，避免资源泄露
 	wg.Wait()
+	return serveErr
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"My_mimiDistributed/registry"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunWritesSnapshotBeforeReturning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, ln, path) }()
+
+	// 注册一个服务，最后一次持久化必须包含它
+	svc := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer svc.Close()
+	data, _ := json.Marshal(registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       svc.URL,
+		ServiceUpdateURL: svc.URL + "/services",
+	})
+	res, err := http.Post("http://"+ln.Addr().String()+"/services", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("registration responded with %v", res.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after cancellation")
+	}
+
+	snapshot, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("snapshot not written before run returned: %v", err)
+	}
+	if !strings.Contains(string(snapshot), svc.URL) {
+		t.Fatalf("snapshot is missing the registered service:\n%s", snapshot)
+	}
+}
+
+func TestRunReturnsServeError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 监听器已关闭，HTTP服务器立即出错，run应返回错误而不是挂起或panic
+	ln.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- run(context.Background(), ln, filepath.Join(t.TempDir(), "registry.json")) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("run returned nil after the server failed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the server failed")
+	}
+}
@@ -0,0 +1,63 @@
+package grades
+
+import (
+	"My_mimiDistributed/registry"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// BatchGrade 是批量添加成绩请求中的一个元素
+type BatchGrade struct {
+	// StudentID 是接收成绩的学生ID
+	StudentID int
+	// Grade 是要添加的成绩
+	Grade Grade
+}
+
+// addGrades 逐个添加成绩，每个元素单独校验和保存，失败的元素不影响其他元素
+// 返回的BatchResult中每个元素的状态码与单独调用POST /students/{id}/grades一致
+func (st *studentStore) addGrades(batch []BatchGrade) registry.BatchResult {
+	var result registry.BatchResult
+	for i, bg := range batch {
+		if err := bg.Grade.Validate(); err != nil {
+			result.Record(i, http.StatusBadRequest, err)
+			continue
+		}
+		if err := st.AddGrade(bg.StudentID, bg.Grade); err != nil {
+			result.Record(i, http.StatusNotFound, err)
+			continue
+		}
+		result.Record(i, http.StatusCreated, nil)
+	}
+	return result
+}
+
+type batchHandler struct{}
+
+// POST /grades/batch
+// 请求体是BatchGrade数组，响应体是registry.BatchResult
+// 全部成功时响应200，部分失败时响应207
+func (bh batchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var batch []BatchGrade
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Println(err)
+		return
+	}
+
+	result := store.addGrades(batch)
+	data, err := studentsHandler{}.toJSON(result)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(result.Status())
+	w.Write(data)
+}
@@ -0,0 +1,63 @@
+package grades
+
+import (
+	"My_mimiDistributed/registry"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withStudents 在测试期间使用只包含ss的内存存储，测试结束后恢复
+func withStudents(t *testing.T, ss Students) {
+	t.Helper()
+	saved := store
+	store = &studentStore{students: ss}
+	t.Cleanup(func() { store = saved })
+}
+
+func TestBatchGradesReportsEachFailure(t *testing.T) {
+	withStudents(t, Students{{ID: 1}, {ID: 2}})
+
+	batch := []BatchGrade{
+		{StudentID: 1, Grade: Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 90}},
+		{StudentID: 99, Grade: Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 80}},
+		{StudentID: 2, Grade: Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 150}},
+		{StudentID: 2, Grade: Grade{Title: "Test 1", Type: GradeTest, Score: 70}},
+	}
+	data, _ := json.Marshal(batch)
+	rec := httptest.NewRecorder()
+	batchHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/grades/batch", bytes.NewReader(data)))
+
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusMultiStatus)
+	}
+	var result registry.BatchResult
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 2 || len(result.Items) != len(batch) {
+		t.Fatalf("result = %+v, want 2 of %d items failed", result, len(batch))
+	}
+	wantStatus := []int{http.StatusCreated, http.StatusNotFound, http.StatusBadRequest, http.StatusCreated}
+	for i, item := range result.Items {
+		if item.Index != i || item.Status != wantStatus[i] {
+			t.Errorf("item %d = %+v, want index %d status %d", i, item, i, wantStatus[i])
+		}
+	}
+	if result.Items[0].Error != "" || result.Items[3].Error != "" {
+		t.Errorf("successful items carry errors: %+v", result.Items)
+	}
+	if !strings.Contains(result.Items[2].Error, "Score") {
+		t.Errorf("item 2 error = %q, want the Score field error", result.Items[2].Error)
+	}
+
+	// 失败的元素不影响其他元素
+	s1, _ := store.GetByID(1)
+	s2, _ := store.GetByID(2)
+	if len(s1.Grades) != 1 || len(s2.Grades) != 1 || s2.Grades[0].Title != "Test 1" {
+		t.Fatalf("grades after batch: student 1 %v, student 2 %v", s1.Grades, s2.Grades)
+	}
+}
@@ -0,0 +1,143 @@
+package grades
+
+import (
+	"My_mimiDistributed/registry"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrNotFound 表示成绩服务中没有请求的学生
+var ErrNotFound = errors.New("student not found")
+
+// Client 是成绩服务的HTTP客户端，负责JSON编解码和状态码检查
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient 创建访问baseURL处成绩服务的客户端，例如http://localhost:6000
+// 使用registry.HTTPClient()发送请求，成绩服务启用TLS时同样适用
+func NewClient(baseURL string) *Client {
+	return &Client{baseURL: baseURL, http: registry.HTTPClient()}
+}
+
+// NewClientFromRegistry 通过服务发现选择一个成绩服务实例并创建客户端
+func NewClientFromRegistry() (*Client, error) {
+	serviceURL, err := registry.GetProvider(registry.GradingService)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(serviceURL), nil
+}
+
+// ListStudents 返回所有学生，等价于使用context.Background()调用ListStudentsContext
+func (c *Client) ListStudents() (Students, error) {
+	return c.ListStudentsContext(context.Background())
+}
+
+// ListStudentsContext 返回所有学生，ctx取消时请求随之中止
+func (c *Client) ListStudentsContext(ctx context.Context) (Students, error) {
+	var ss Students
+	if err := c.get(ctx, "/students", &ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// OpenStudentsContext 请求所有学生，返回未解码的JSON数组响应体，调用方负责关闭
+// 学生列表很大时可以边读取边解码，不必把整个列表保存在内存中
+func (c *Client) OpenStudentsContext(ctx context.Context) (io.ReadCloser, error) {
+	return c.open(ctx, "/students")
+}
+
+// GetStudent 返回指定学生，等价于使用context.Background()调用GetStudentContext
+func (c *Client) GetStudent(id int) (*Student, error) {
+	return c.GetStudentContext(context.Background(), id)
+}
+
+// GetStudentContext 返回指定学生，学生不存在时返回ErrNotFound
+func (c *Client) GetStudentContext(ctx context.Context, id int) (*Student, error) {
+	var s Student
+	if err := c.get(ctx, fmt.Sprintf("/students/%v", id), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// AddGrade 为指定学生添加成绩，等价于使用context.Background()调用AddGradeContext
+func (c *Client) AddGrade(id int, g Grade) error {
+	return c.AddGradeContext(context.Background(), id, g)
+}
+
+// AddGradeContext 为指定学生添加成绩
+// 学生不存在时返回ErrNotFound，成绩校验失败时返回*FieldError
+func (c *Client) AddGradeContext(ctx context.Context, id int, g Grade) error {
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%v/students/%v/grades", c.baseURL, id), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusCreated:
+		return nil
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusBadRequest:
+		fe := new(FieldError)
+		if err := json.NewDecoder(res.Body).Decode(fe); err != nil || fe.Field == "" {
+			return fmt.Errorf("grading service rejected grade for student %v", id)
+		}
+		return fe
+	default:
+		return fmt.Errorf("grading service responded with code %v", res.StatusCode)
+	}
+}
+
+// get 请求path并把JSON响应解码到v
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	body, err := c.open(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	return json.NewDecoder(body).Decode(v)
+}
+
+// open 请求path，状态码为200时返回响应体，调用方负责关闭
+func (c *Client) open(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res.Body, nil
+	case http.StatusNotFound:
+		res.Body.Close()
+		return nil, ErrNotFound
+	default:
+		res.Body.Close()
+		return nil, fmt.Errorf("grading service responded with code %v", res.StatusCode)
+	}
+}
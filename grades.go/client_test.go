@@ -0,0 +1,101 @@
+package grades
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gradingServer 启动使用真实路由的成绩服务，返回访问它的客户端
+func gradingServer(t *testing.T, ss Students) *Client {
+	t.Helper()
+	withStudents(t, ss)
+	srv := httptest.NewServer(gradesMux())
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL)
+}
+
+func TestClientListStudents(t *testing.T) {
+	c := gradingServer(t, Students{{ID: 1}, {ID: 2}})
+
+	ss, err := c.ListStudents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 2 || ss[0].ID != 1 || ss[1].ID != 2 {
+		t.Errorf("ListStudents() = %+v, want ids 1 and 2", ss)
+	}
+}
+
+func TestClientGetStudent(t *testing.T) {
+	c := gradingServer(t, Students{{ID: 1, FirstName: "Ada"}})
+
+	s, err := c.GetStudent(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ID != 1 || s.FirstName != "Ada" {
+		t.Errorf("GetStudent(1) = %+v, want Ada", s)
+	}
+
+	if _, err := c.GetStudent(99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetStudent(99) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestClientAddGrade(t *testing.T) {
+	c := gradingServer(t, Students{{ID: 1}})
+
+	g := Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 90}
+	if err := c.AddGrade(1, g); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := store.GetByID(1); len(s.Grades) != 1 || s.Grades[0] != g {
+		t.Errorf("grades = %+v, want %+v", s.Grades, g)
+	}
+
+	if err := c.AddGrade(99, g); !errors.Is(err, ErrNotFound) {
+		t.Errorf("AddGrade(99) error = %v, want ErrNotFound", err)
+	}
+
+	var fe *FieldError
+	if err := c.AddGrade(1, Grade{Title: "Quiz 2", Type: GradeQuiz, Score: 150}); !errors.As(err, &fe) || fe.Field != "Score" {
+		t.Errorf("AddGrade with bad score error = %v, want a Score *FieldError", err)
+	}
+}
+
+func TestClientReportsUnexpectedStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	if _, err := c.ListStudents(); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("ListStudents() error = %v, want a status error", err)
+	}
+	if err := c.AddGrade(1, Grade{Title: "Quiz 1", Type: GradeQuiz}); err == nil {
+		t.Error("AddGrade succeeded against a failing service")
+	}
+}
+
+func TestClientCanceledMidFlight(t *testing.T) {
+	entered := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-entered
+		cancel()
+	}()
+	if _, err := c.GetStudentContext(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetStudentContext = %v, want context.Canceled", err)
+	}
+}
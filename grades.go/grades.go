@@ -1,8 +1,8 @@
 package grades
 
 import (
+	"encoding/json"
 	"fmt"
-	"sync"
 )
 
 type Student struct {
@@ -12,7 +12,11 @@ type Student struct {
 	Grades    []Grade
 }
 
+// Average 返回所有成绩的算术平均分，没有成绩时返回0
 func (s Student) Average() float32 {
+	if len(s.Grades) == 0 {
+		return 0
+	}
 	var result float32
 	for _, grade := range s.Grades {
 		result += grade.Score
@@ -20,6 +24,41 @@ func (s Student) Average() float32 {
 	return result / float32(len(s.Grades))
 }
 
+// DefaultGradeWeights 是加权平均分的默认权重，考试按两倍计算
+var DefaultGradeWeights = map[GradeType]float32{
+	GradeQuiz: 1,
+	GradeTest: 1,
+	GradeExam: 2,
+}
+
+// WeightedAverage 按成绩类型加权计算平均分
+// weights中没有的类型权重为1，总权重为0时返回0
+func (s Student) WeightedAverage(weights map[GradeType]float32) float32 {
+	var sum, total float32
+	for _, grade := range s.Grades {
+		w, ok := weights[grade.Type]
+		if !ok {
+			w = 1
+		}
+		sum += grade.Score * w
+		total += w
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
+
+// MarshalJSON 在学生的JSON中附带平均分
+func (s Student) MarshalJSON() ([]byte, error) {
+	type student Student
+	return json.Marshal(struct {
+		student
+		Average         float32
+		WeightedAverage float32
+	}{student(s), s.Average(), s.WeightedAverage(DefaultGradeWeights)})
+}
+
 const (
 	GradeQuiz = GradeType("Quiz")
 	GradeTest = GradeType("Test")
@@ -33,12 +72,34 @@ type Grade struct {
 	Score float32
 }
 
-type Students []Student
+// FieldError 描述成绩中某个字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"error"`
+}
 
-var (
-	students      Students
-	studentsMutex sync.Mutex
-)
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate 校验成绩: 标题非空，类型为已定义的类型，分数在0到100之间
+// 校验失败时返回*FieldError
+func (g Grade) Validate() error {
+	if g.Title == "" {
+		return &FieldError{Field: "Title", Message: "title must not be empty"}
+	}
+	switch g.Type {
+	case GradeQuiz, GradeTest, GradeExam:
+	default:
+		return &FieldError{Field: "Type", Message: fmt.Sprintf("unknown grade type %q", g.Type)}
+	}
+	if g.Score < 0 || g.Score > 100 {
+		return &FieldError{Field: "Score", Message: fmt.Sprintf("score %v is not between 0 and 100", g.Score)}
+	}
+	return nil
+}
+
+type Students []Student
 
 func (ss Students) GetByID(id int) (*Student, error) {
 	for i := range ss {
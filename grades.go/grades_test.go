@@ -0,0 +1,112 @@
+package grades
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAverageOfNoGradesIsZero(t *testing.T) {
+	var s Student
+	if got := s.Average(); got != 0 || math.IsNaN(float64(got)) {
+		t.Errorf("Average() = %v, want 0", got)
+	}
+	if got := s.WeightedAverage(DefaultGradeWeights); got != 0 || math.IsNaN(float64(got)) {
+		t.Errorf("WeightedAverage() = %v, want 0", got)
+	}
+}
+
+func TestWeightedAndUnweightedAverage(t *testing.T) {
+	s := Student{Grades: []Grade{
+		{Title: "Quiz 1", Type: GradeQuiz, Score: 60},
+		{Title: "Final", Type: GradeExam, Score: 90},
+	}}
+
+	if got := s.Average(); got != 75 {
+		t.Errorf("Average() = %v, want 75", got)
+	}
+	if got := s.WeightedAverage(DefaultGradeWeights); got != 80 {
+		t.Errorf("WeightedAverage(default) = %v, want 80", got)
+	}
+	custom := map[GradeType]float32{GradeQuiz: 3}
+	if got := s.WeightedAverage(custom); got != 67.5 {
+		t.Errorf("WeightedAverage(custom) = %v, want 67.5", got)
+	}
+}
+
+func TestStudentJSONCarriesAverage(t *testing.T) {
+	s := Student{ID: 1, Grades: []Grade{
+		{Title: "Quiz 1", Type: GradeQuiz, Score: 60},
+		{Title: "Final", Type: GradeExam, Score: 90},
+	}}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		ID              int
+		Average         float32
+		WeightedAverage float32
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.Average != 75 || got.WeightedAverage != 80 {
+		t.Errorf("JSON = %s, want id 1 with averages 75 and 80", data)
+	}
+}
+
+// validationField 返回Validate失败时出错的字段名
+func validationField(t *testing.T, g Grade) string {
+	t.Helper()
+	var fe *FieldError
+	if err := g.Validate(); !errors.As(err, &fe) {
+		t.Fatalf("Validate(%+v) = %v, want a *FieldError", g, err)
+	}
+	return fe.Field
+}
+
+func TestGradeValidate(t *testing.T) {
+	valid := Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 100}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate(%+v) = %v, want nil", valid, err)
+	}
+
+	if f := validationField(t, Grade{Type: GradeQuiz, Score: 50}); f != "Title" {
+		t.Errorf("empty title flagged %v, want Title", f)
+	}
+	if f := validationField(t, Grade{Title: "Lab", Type: "Lab", Score: 50}); f != "Type" {
+		t.Errorf("unknown type flagged %v, want Type", f)
+	}
+	if f := validationField(t, Grade{Title: "Quiz 1", Type: GradeQuiz, Score: -1}); f != "Score" {
+		t.Errorf("negative score flagged %v, want Score", f)
+	}
+	if f := validationField(t, Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 100.5}); f != "Score" {
+		t.Errorf("score above 100 flagged %v, want Score", f)
+	}
+}
+
+func TestAddGradeRejectsInvalidField(t *testing.T) {
+	withStudents(t, Students{{ID: 1}})
+
+	body := `{"Title":"Quiz 1","Type":"Quiz","Score":120}`
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("POST", "/students/1/grades", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %v, want 400", rec.Code)
+	}
+	var fe FieldError
+	if err := json.NewDecoder(rec.Body).Decode(&fe); err != nil {
+		t.Fatal(err)
+	}
+	if fe.Field != "Score" || fe.Message == "" {
+		t.Errorf("error body = %+v, want the Score field described", fe)
+	}
+	if s, _ := store.GetByID(1); len(s.Grades) != 0 {
+		t.Errorf("invalid grade was stored: %+v", s.Grades)
+	}
+}
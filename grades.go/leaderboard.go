@@ -0,0 +1,64 @@
+package grades
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+const defaultLeaderboardSize = 10
+
+// rank 返回按平均分从高到低排序的学生副本，平均分相同时按ID升序
+// 没有成绩的学生排在最后
+func rank(ss Students) Students {
+	ranked := make(Students, len(ss))
+	copy(ranked, ss)
+	score := func(s Student) float32 {
+		if len(s.Grades) == 0 {
+			return -1
+		}
+		return s.Average()
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		si, sj := score(ranked[i]), score(ranked[j])
+		if si != sj {
+			return si > sj
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	return ranked
+}
+
+type leaderboardHandler struct{}
+
+// /leaderboard?n=5
+func (lh leaderboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	n := defaultLeaderboardSize
+	if v := r.URL.Query().Get("n"); v != "" {
+		var err error
+		n, err = strconv.Atoi(v)
+		if err != nil || n < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	ranked := rank(store.GetAll())
+
+	if n > len(ranked) {
+		n = len(ranked)
+	}
+	data, err := studentsHandler{}.toJSON(ranked[:n])
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
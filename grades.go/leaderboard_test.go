@@ -0,0 +1,70 @@
+package grades
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// leaderboardIDs 请求/leaderboard并返回按名次排列的学生ID
+func leaderboardIDs(t *testing.T, query string) []int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	leaderboardHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/leaderboard"+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /leaderboard%v = %v, want %v", query, rec.Code, http.StatusOK)
+	}
+	var ss Students
+	if err := json.NewDecoder(rec.Body).Decode(&ss); err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, len(ss))
+	for i, s := range ss {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func quiz(score float32) []Grade {
+	return []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: score}}
+}
+
+func TestLeaderboardOrdersByAverageThenID(t *testing.T) {
+	withStudents(t, Students{
+		{ID: 4, Grades: quiz(70)},
+		{ID: 3, Grades: quiz(90)},
+		{ID: 1},
+		{ID: 2, Grades: quiz(90)},
+		{ID: 5, Grades: quiz(80)},
+	})
+
+	// 平均分相同的2和3按ID排序，没有成绩的1排在最后
+	if got := fmt.Sprint(leaderboardIDs(t, "?n=5")); got != "[2 3 5 4 1]" {
+		t.Errorf("leaderboard = %v, want [2 3 5 4 1]", got)
+	}
+	if got := fmt.Sprint(leaderboardIDs(t, "?n=2")); got != "[2 3]" {
+		t.Errorf("top 2 = %v, want [2 3]", got)
+	}
+}
+
+func TestLeaderboardClampsNToClassSize(t *testing.T) {
+	withStudents(t, Students{{ID: 1, Grades: quiz(60)}, {ID: 2, Grades: quiz(80)}})
+
+	if got := fmt.Sprint(leaderboardIDs(t, "?n=50")); got != "[2 1]" {
+		t.Errorf("leaderboard with n larger than the class = %v, want [2 1]", got)
+	}
+}
+
+func TestLeaderboardRejectsInvalidN(t *testing.T) {
+	withStudents(t, Students{{ID: 1}})
+
+	for _, query := range []string{"?n=0", "?n=-3", "?n=five"} {
+		rec := httptest.NewRecorder()
+		leaderboardHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/leaderboard"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET /leaderboard%v = %v, want %v", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package grades
 
 func init() {
-	students = []Student{
+	store.students = Students{
 		{
 			ID:        1,
 			FirstName: "harusame",
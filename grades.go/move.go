@@ -0,0 +1,71 @@
+package grades
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// GradeMove 描述把一条成绩从一个学生移动到另一个学生
+type GradeMove struct {
+	// FromID 是成绩当前所属学生的ID
+	FromID int
+	// Index 是成绩在源学生Grades中的下标
+	Index int
+	// ToID 是目标学生的ID
+	ToID int
+}
+
+// moveGrade 把成绩从源学生移动到目标学生末尾，调用方必须持有store.mu的写锁
+// 学生不存在或下标越界时返回错误，不做任何修改
+func (ss Students) moveGrade(m GradeMove) (Grade, error) {
+	from, err := ss.GetByID(m.FromID)
+	if err != nil {
+		return Grade{}, err
+	}
+	to, err := ss.GetByID(m.ToID)
+	if err != nil {
+		return Grade{}, err
+	}
+	if m.Index < 0 || m.Index >= len(from.Grades) {
+		return Grade{}, fmt.Errorf("grade index %v out of range for student %v",
+			m.Index, m.FromID)
+	}
+	g := from.Grades[m.Index]
+	from.Grades = append(from.Grades[:m.Index:m.Index], from.Grades[m.Index+1:]...)
+	to.Grades = append(to.Grades, g)
+	return g, nil
+}
+
+type moveHandler struct{}
+
+// POST /grades/move
+func (mh moveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var m GradeMove
+	err := json.NewDecoder(r.Body).Decode(&m)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Println(err)
+		return
+	}
+
+	g, err := store.MoveGrade(m)
+
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		log.Println(err)
+		return
+	}
+	data, err := studentsHandler{}.toJSON(g)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,107 @@
+package grades
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// postMove 向moveHandler发送body并返回响应
+func postMove(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	moveHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/grades/move", strings.NewReader(body)))
+	return rec
+}
+
+// titles 返回学生id的成绩标题
+func titles(t *testing.T, id int) []string {
+	t.Helper()
+	s, err := store.GetByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for _, g := range s.Grades {
+		result = append(result, g.Title)
+	}
+	return result
+}
+
+func movableStudents() Students {
+	return Students{
+		{ID: 1, Grades: []Grade{
+			{Title: "Quiz 1", Type: GradeQuiz, Score: 80},
+			{Title: "Quiz 2", Type: GradeQuiz, Score: 90},
+			{Title: "Quiz 3", Type: GradeQuiz, Score: 70},
+		}},
+		{ID: 2, Grades: []Grade{{Title: "Test 1", Type: GradeTest, Score: 60}}},
+	}
+}
+
+func TestMoveGradeBetweenStudents(t *testing.T) {
+	withStudents(t, movableStudents())
+
+	rec := postMove(t, `{"FromID":1,"Index":1,"ToID":2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /grades/move = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var moved Grade
+	if err := json.NewDecoder(rec.Body).Decode(&moved); err != nil {
+		t.Fatal(err)
+	}
+	if moved.Title != "Quiz 2" {
+		t.Errorf("moved grade = %+v, want Quiz 2", moved)
+	}
+	if got := fmt.Sprint(titles(t, 1)); got != "[Quiz 1 Quiz 3]" {
+		t.Errorf("source grades = %v, want [Quiz 1 Quiz 3]", got)
+	}
+	if got := fmt.Sprint(titles(t, 2)); got != "[Test 1 Quiz 2]" {
+		t.Errorf("destination grades = %v, want [Test 1 Quiz 2]", got)
+	}
+}
+
+func TestMoveGradeMissingSourceStudent(t *testing.T) {
+	withStudents(t, movableStudents())
+	if rec := postMove(t, `{"FromID":9,"Index":0,"ToID":2}`); rec.Code != http.StatusNotFound {
+		t.Errorf("move from a missing student = %v, want %v", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestMoveGradeMissingDestinationStudent(t *testing.T) {
+	withStudents(t, movableStudents())
+	if rec := postMove(t, `{"FromID":1,"Index":0,"ToID":9}`); rec.Code != http.StatusNotFound {
+		t.Errorf("move to a missing student = %v, want %v", rec.Code, http.StatusNotFound)
+	}
+	// 失败的移动不修改源学生
+	if got := len(titles(t, 1)); got != 3 {
+		t.Errorf("source has %d grades after a failed move, want 3", got)
+	}
+}
+
+func TestMoveGradeIndexOutOfRange(t *testing.T) {
+	withStudents(t, movableStudents())
+	for _, body := range []string{`{"FromID":1,"Index":3,"ToID":2}`, `{"FromID":1,"Index":-1,"ToID":2}`} {
+		if rec := postMove(t, body); rec.Code != http.StatusNotFound {
+			t.Errorf("move %v = %v, want %v", body, rec.Code, http.StatusNotFound)
+		}
+	}
+	if got := len(titles(t, 2)); got != 1 {
+		t.Errorf("destination has %d grades after failed moves, want 1", got)
+	}
+}
+
+func TestMoveGradeRejectsBadRequests(t *testing.T) {
+	withStudents(t, movableStudents())
+	if rec := postMove(t, `not json`); rec.Code != http.StatusBadRequest {
+		t.Errorf("move with an invalid body = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+	rec := httptest.NewRecorder()
+	moveHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/grades/move", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /grades/move = %v, want %v", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
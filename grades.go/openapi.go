@@ -0,0 +1,103 @@
+package grades
+
+import (
+	"log"
+	"net/http"
+)
+
+// operation 描述一个接口操作
+type operation struct {
+	Summary    string              `json:"summary"`
+	Parameters []parameter         `json:"parameters,omitempty"`
+	Responses  map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type response struct {
+	Description string `json:"description"`
+}
+
+var idParam = parameter{Name: "id", In: "path", Required: true,
+	Schema: map[string]string{"type": "integer"}}
+
+// openAPISpec 是成绩服务的最小OpenAPI 3描述
+// 新增接口时需要同步更新
+var openAPISpec = map[string]interface{}{
+	"openapi": "3.0.3",
+	"info": map[string]string{
+		"title":   "Grading Service",
+		"version": "1.0.0",
+	},
+	"paths": map[string]map[string]operation{
+		"/students": {
+			"get": {Summary: "List all students",
+				Responses: map[string]response{"200": {"students"}}},
+		},
+		"/students/search": {
+			"get": {Summary: "Find students whose first or last name contains name, case-insensitively",
+				Parameters: []parameter{{Name: "name", In: "query", Required: true,
+					Schema: map[string]string{"type": "string"}}},
+				Responses: map[string]response{"200": {"matching students"}, "400": {"missing name"}}},
+		},
+		"/students/{id}": {
+			"get": {Summary: "Get a student with grades, optionally filtered by score range",
+				Parameters: []parameter{idParam,
+					{Name: "minScore", In: "query", Schema: map[string]string{"type": "number"}},
+					{Name: "maxScore", In: "query", Schema: map[string]string{"type": "number"}}},
+				Responses: map[string]response{"200": {"student"}, "400": {"invalid score range"},
+					"404": {"student not found"}}},
+		},
+		"/students/{id}/grades": {
+			"post": {Summary: "Append a grade to a student",
+				Parameters: []parameter{idParam},
+				Responses: map[string]response{"201": {"grade created"}, "400": {"invalid grade"},
+					"404": {"student not found"}}},
+		},
+		"/stats": {
+			"get": {Summary: "Class-wide statistics of student averages, or of one assignment's scores when title is set",
+				Parameters: []parameter{{Name: "title", In: "query",
+					Schema: map[string]string{"type": "string"}}},
+				Responses: map[string]response{"200": {"statistics"}, "404": {"no grades with this title"}}},
+		},
+		"/leaderboard": {
+			"get": {Summary: "Top N students by average",
+				Parameters: []parameter{{Name: "n", In: "query",
+					Schema: map[string]string{"type": "integer"}}},
+				Responses: map[string]response{"200": {"ranked students"}, "400": {"invalid n"}}},
+		},
+		"/grades/move": {
+			"post": {Summary: "Move a grade from one student to another",
+				Responses: map[string]response{"200": {"moved grade"}, "400": {"invalid request"},
+					"404": {"student or grade not found"}}},
+		},
+		"/grades/batch": {
+			"post": {Summary: "Add several grades, reporting success or failure per item",
+				Responses: map[string]response{"200": {"all grades added"},
+					"207": {"some grades failed; see Items for each index"}, "400": {"invalid request"}}},
+		},
+	},
+}
+
+type openAPIHandler struct{}
+
+// GET /openapi.json
+func (oh openAPIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := studentsHandler{}.toJSON(openAPISpec)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
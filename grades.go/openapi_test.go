@@ -0,0 +1,48 @@
+package grades
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPIListsKnownPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var doc struct {
+		OpenAPI string                     `json:"openapi"`
+		Paths   map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi version = %q, want 3.x", doc.OpenAPI)
+	}
+	for _, path := range []string{"/students", "/students/{id}", "/students/{id}/grades", "/stats",
+		"/leaderboard", "/grades/move", "/grades/batch"} {
+		if _, ok := doc.Paths[path]; !ok {
+			t.Errorf("document does not describe %v", path)
+		}
+	}
+
+	// 文档中的每个路径都必须有对应的处理器
+	for path := range doc.Paths {
+		concrete := strings.NewReplacer("{id}", "1").Replace(path)
+		if _, pattern := mux.Handler(httptest.NewRequest(http.MethodGet, concrete, nil)); pattern == "" {
+			t.Errorf("documented path %v has no handler", path)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package grades
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+)
+
+// LoadStudents 从path加载学生数据，之后每次修改成绩都会写回该文件
+// 文件不存在时保留内置的模拟数据，并在第一次修改时创建文件
+func LoadStudents(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	var loaded Students
+	if err == nil {
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return err
+		}
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.path = path
+	if loaded != nil {
+		store.students = loaded
+		store.stats.invalidate()
+	}
+	return nil
+}
+
+// saveLocked 把学生数据写入store.path，未设置路径时不做任何事
+// 先写临时文件再重命名，避免写到一半时留下损坏的文件；调用方必须持有store.mu的写锁
+func (st *studentStore) saveLocked() {
+	if st.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(st.students, "", "  ")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	tmp := st.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := os.Rename(tmp, st.path); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,59 @@
+package grades
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddedGradeSurvivesReload(t *testing.T) {
+	withStudents(t, Students{{ID: 1, FirstName: "Ada"}})
+	path := filepath.Join(t.TempDir(), "students.json")
+	if err := LoadStudents(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.AddGrade(1, Grade{Title: "Quiz 1", Type: GradeQuiz, Score: 77}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 换成空的store后从磁盘重新加载，模拟重启
+	store = new(studentStore)
+	if err := LoadStudents(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := store.GetByID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.FirstName != "Ada" || len(s.Grades) != 1 || s.Grades[0].Score != 77 {
+		t.Errorf("reloaded student = %+v, want Ada with the added quiz", s)
+	}
+}
+
+func TestLoadStudentsKeepsMockDataWhenFileIsAbsent(t *testing.T) {
+	withStudents(t, Students{{ID: 1}, {ID: 2}})
+	path := filepath.Join(t.TempDir(), "missing.json")
+
+	if err := LoadStudents(path); err != nil {
+		t.Fatalf("LoadStudents(missing) = %v, want nil", err)
+	}
+	if n := len(store.GetAll()); n != 2 {
+		t.Errorf("got %v students, want the 2 already in memory", n)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file was created before any change: %v", err)
+	}
+}
+
+func TestLoadStudentsRejectsCorruptFile(t *testing.T) {
+	withStudents(t, Students{{ID: 1}})
+	path := filepath.Join(t.TempDir(), "students.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadStudents(path); err == nil {
+		t.Error("LoadStudents accepted a corrupt file")
+	}
+}
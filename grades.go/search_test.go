@@ -0,0 +1,78 @@
+package grades
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// searchIDs 请求/students/search?name=name并返回匹配学生的ID
+func searchIDs(t *testing.T, name string) []int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", "/students/search?name="+name, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("search %q status = %v, want 200", name, rec.Code)
+	}
+	var ss []Student
+	if err := json.NewDecoder(rec.Body).Decode(&ss); err != nil {
+		t.Fatal(err)
+	}
+	if ss == nil {
+		t.Fatalf("search %q returned null, want an array", name)
+	}
+	ids := make([]int, len(ss))
+	for i, s := range ss {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func searchStudents() Students {
+	return Students{
+		{ID: 1, FirstName: "harusame", LastName: "Z"},
+		{ID: 2, FirstName: "Ada", LastName: "Lovelace"},
+		{ID: 3, FirstName: "Alan", LastName: "Turing"},
+	}
+}
+
+func TestSearchExactMatch(t *testing.T) {
+	withStudents(t, searchStudents())
+	if ids := searchIDs(t, "harusame"); len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("ids = %v, want [1]", ids)
+	}
+	if ids := searchIDs(t, "Turing"); len(ids) != 1 || ids[0] != 3 {
+		t.Errorf("last name ids = %v, want [3]", ids)
+	}
+}
+
+func TestSearchPartialMatch(t *testing.T) {
+	withStudents(t, searchStudents())
+	if ids := searchIDs(t, "la"); len(ids) != 2 || ids[0] != 2 || ids[1] != 3 {
+		t.Errorf("ids = %v, want [2 3]", ids)
+	}
+}
+
+func TestSearchNoMatchIsEmptyArray(t *testing.T) {
+	withStudents(t, searchStudents())
+	if ids := searchIDs(t, "nobody"); len(ids) != 0 {
+		t.Errorf("ids = %v, want none", ids)
+	}
+}
+
+func TestSearchIsCaseInsensitive(t *testing.T) {
+	withStudents(t, searchStudents())
+	if ids := searchIDs(t, "HARUSAME"); len(ids) != 1 || ids[0] != 1 {
+		t.Errorf("ids = %v, want [1]", ids)
+	}
+}
+
+func TestSearchRequiresName(t *testing.T) {
+	withStudents(t, searchStudents())
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", "/students/search", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %v, want 400", rec.Code)
+	}
+}
@@ -10,12 +10,22 @@ import (
 	"strings"
 )
 
-func RegisterHandlers() {
+func RegisterHandlers(mux *http.ServeMux) {
 	handler := new(studentsHandler)
 	//学生集合
-	http.Handle("/students", handler)
+	mux.Handle("/students", handler)
 	//单个学生
-	http.Handle("/students/", handler)
+	mux.Handle("/students/", handler)
+	//全班统计
+	mux.Handle("/stats", new(statsHandler))
+	//排行榜
+	mux.Handle("/leaderboard", new(leaderboardHandler))
+	//在学生之间移动成绩
+	mux.Handle("/grades/move", new(moveHandler))
+	//批量添加成绩
+	mux.Handle("/grades/batch", new(batchHandler))
+	//接口描述
+	mux.Handle("/openapi.json", new(openAPIHandler))
 
 }
 
@@ -23,34 +33,53 @@ type studentsHandler struct{}
 
 // /students
 // /students/{id}
+// /students/search?name=
 // /students/{id} /grades
 func (sh studentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	pathSegments := strings.Split(r.URL.Path, "/")
 	switch len(pathSegments) {
 	case 2:
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 		sh.getAll(w, r)
 	case 3:
+		if pathSegments[2] == "search" {
+			if r.Method != http.MethodGet {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			sh.search(w, r)
+			return
+		}
 		id, err := strconv.Atoi(pathSegments[2])
 		if err != nil {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 		sh.getOne(w, r, id)
 	case 4:
 		id, err := strconv.Atoi(pathSegments[2])
-		if err != nil {
+		if err != nil || pathSegments[3] != "grades" {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 		sh.addGrade(w, r, id)
 	default:
 		w.WriteHeader(http.StatusNotFound)
 	}
 }
 func (sh studentsHandler) getAll(w http.ResponseWriter, r *http.Request) {
-	studentsMutex.Lock()
-	defer studentsMutex.Unlock()
-	data, err := sh.toJSON(students)
+	data, err := sh.toJSON(store.GetAll())
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Println(err)
@@ -61,6 +90,22 @@ func (sh studentsHandler) getAll(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (sh studentsHandler) search(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	data, err := sh.toJSON(store.Search(name))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
+
 func (sh studentsHandler) toJSON(obj interface{}) ([]byte, error) {
 	var b bytes.Buffer
 	enc := json.NewEncoder(&b)
@@ -72,15 +117,26 @@ func (sh studentsHandler) toJSON(obj interface{}) ([]byte, error) {
 }
 
 func (sh studentsHandler) getOne(w http.ResponseWriter, r *http.Request, id int) {
-	studentsMutex.Lock()
-	defer studentsMutex.Unlock()
-	student, err := students.GetByID(id)
+	minScore, maxScore, err := scoreRange(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		log.Println(err)
+		return
+	}
+	student, err := store.GetByID(id)
 	if err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		log.Println(err)
 		return
 	}
-	data, err := sh.toJSON(student)
+	filtered := student
+	filtered.Grades = make([]Grade, 0, len(student.Grades))
+	for _, g := range student.Grades {
+		if g.Score >= minScore && g.Score <= maxScore {
+			filtered.Grades = append(filtered.Grades, g)
+		}
+	}
+	data, err := sh.toJSON(filtered)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		log.Printf("failed to sericialize student : %q", err)
@@ -92,30 +148,58 @@ func (sh studentsHandler) getOne(w http.ResponseWriter, r *http.Request, id int)
 
 }
 
+// scoreRange 读取查询参数minScore和maxScore，缺省时为0和100
+// 两者必须在0到100之间且minScore不大于maxScore
+func scoreRange(r *http.Request) (float32, float32, error) {
+	bounds := [2]float32{0, 100}
+	for i, name := range []string{"minScore", "maxScore"} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		// 写成!(0 <= f <= 100)的形式，NaN参与的比较总为false，也会被拒绝
+		f, err := strconv.ParseFloat(v, 32)
+		if err != nil || !(f >= 0 && f <= 100) {
+			return 0, 0, fmt.Errorf("%v must be a number between 0 and 100, got %q", name, v)
+		}
+		bounds[i] = float32(f)
+	}
+	if bounds[0] > bounds[1] {
+		return 0, 0, fmt.Errorf("minScore %v is greater than maxScore %v", bounds[0], bounds[1])
+	}
+	return bounds[0], bounds[1], nil
+}
+
 func (sh studentsHandler) addGrade(w http.ResponseWriter, r *http.Request, id int) {
-	studentsMutex.Lock()
-	defer studentsMutex.Unlock()
-	student, err := students.GetByID(id)
+	var g Grade
+	dec := json.NewDecoder(r.Body)
+	err := dec.Decode(&g)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		w.WriteHeader(http.StatusBadRequest)
 		log.Println(err)
 		return
 	}
-	var g Grade
-	dec := json.NewDecoder(r.Body)
-	err = dec.Decode(&g)
-	if err != nil {
+	if err := g.Validate(); err != nil {
+		data, _ := sh.toJSON(err)
+		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
+		w.Write(data)
+		return
+	}
+	err = store.AddGrade(id, g)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
 		log.Println(err)
 		return
 	}
-	student.Grades = append(student.Grades, g)
-	w.WriteHeader(http.StatusCreated)
 	data, err := sh.toJSON(g)
 	if err != nil {
+		w.WriteHeader(http.StatusCreated)
 		log.Println(err)
 		return
 	}
+	//响应头必须在WriteHeader之前设置
 	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	w.Write(data)
 }
@@ -0,0 +1,125 @@
+package grades
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestScoreRangeRejectsNaN(t *testing.T) {
+	for _, query := range []string{"minScore=NaN", "maxScore=nan", "minScore=-1", "maxScore=101"} {
+		r := httptest.NewRequest("GET", "/students?"+query, nil)
+		if _, _, err := scoreRange(r); err == nil {
+			t.Errorf("scoreRange accepted %v", query)
+		}
+	}
+}
+
+// gradesMux 返回注册了成绩服务全部路由的mux
+func gradesMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	return mux
+}
+
+func TestGetAllStudents(t *testing.T) {
+	withStudents(t, Students{{ID: 1, FirstName: "Ada"}, {ID: 2, FirstName: "Alan"}})
+
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", "/students", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200", rec.Code)
+	}
+	var got Students
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Errorf("students = %+v, want ids 1 and 2", got)
+	}
+}
+
+func TestGetOneStudent(t *testing.T) {
+	withStudents(t, Students{{ID: 1, FirstName: "Ada"}})
+
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", "/students/1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200", rec.Code)
+	}
+	var got Student
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1 || got.FirstName != "Ada" {
+		t.Errorf("student = %+v, want Ada with id 1", got)
+	}
+
+	rec = httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", "/students/99", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown id status = %v, want 404", rec.Code)
+	}
+}
+
+func TestAddGrade(t *testing.T) {
+	withStudents(t, Students{{ID: 1}})
+
+	body := `{"Title":"Quiz 1","Type":"Quiz","Score":88}`
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("POST", "/students/1/grades", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %v, want 201", rec.Code)
+	}
+	s, _ := store.GetByID(1)
+	if len(s.Grades) != 1 || s.Grades[0].Title != "Quiz 1" || s.Grades[0].Score != 88 {
+		t.Errorf("grades = %+v, want the posted quiz", s.Grades)
+	}
+
+	rec = httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("POST", "/students/99/grades", strings.NewReader(body)))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unknown id status = %v, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("POST", "/students/1/grades", strings.NewReader(`{"Title":""}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid grade status = %v, want 400", rec.Code)
+	}
+}
+
+func TestAddGradeConcurrently(t *testing.T) {
+	withStudents(t, Students{{ID: 1}})
+	mux := gradesMux()
+
+	const n = 20
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"Title":"Quiz %d","Type":"Quiz","Score":50}`, i)
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("POST", "/students/1/grades", strings.NewReader(body)))
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %v, want 201", rec.Code)
+			}
+		}(i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/students", nil))
+		}()
+	}
+	wg.Wait()
+
+	s, _ := store.GetByID(1)
+	if len(s.Grades) != n {
+		t.Errorf("got %v grades, want %v", len(s.Grades), n)
+	}
+}
@@ -0,0 +1,108 @@
+package grades
+
+import (
+	"log"
+	"net/http"
+	"sort"
+)
+
+// ClassStats 是一组分数的汇总统计，用于全班平均分或单项作业的分数
+type ClassStats struct {
+	Count  int
+	Mean   float32
+	Median float32
+	Min    float32
+	Max    float32
+}
+
+// statsCache 缓存全班统计结果，成绩变化时失效
+// 作为studentStore的字段，由store.mu保护
+type statsCache struct {
+	valid bool
+	stats ClassStats
+}
+
+// get 返回缓存的统计结果，缓存失效时重新计算
+// 调用方必须持有store.mu的写锁
+func (c *statsCache) get(ss Students) ClassStats {
+	if !c.valid {
+		c.stats = computeClassStats(ss)
+		c.valid = true
+	}
+	return c.stats
+}
+
+// invalidate 使缓存失效，下次读取时重新计算
+// 每次修改成绩后调用，调用方必须持有store.mu的写锁
+func (c *statsCache) invalidate() {
+	c.valid = false
+}
+
+// computeClassStats 根据每个学生的平均分计算全班统计，没有成绩的学生不参与统计
+func computeClassStats(ss Students) ClassStats {
+	averages := make([]float32, 0, len(ss))
+	for _, s := range ss {
+		if len(s.Grades) > 0 {
+			averages = append(averages, s.Average())
+		}
+	}
+	return summarize(averages)
+}
+
+// summarize 计算一组分数的统计，会对scores原地排序
+// 偶数个分数时中位数取中间两个的平均值
+func summarize(scores []float32) ClassStats {
+	if len(scores) == 0 {
+		return ClassStats{}
+	}
+	sort.Slice(scores, func(i, j int) bool { return scores[i] < scores[j] })
+
+	var sum float32
+	for _, score := range scores {
+		sum += score
+	}
+	n := len(scores)
+	median := scores[n/2]
+	if n%2 == 0 {
+		median = (scores[n/2-1] + scores[n/2]) / 2
+	}
+	return ClassStats{
+		Count:  n,
+		Mean:   sum / float32(n),
+		Median: median,
+		Min:    scores[0],
+		Max:    scores[n-1],
+	}
+}
+
+type statsHandler struct{}
+
+// /stats
+// /stats?title=Quiz%201
+func (sh statsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var stats ClassStats
+	if title := r.URL.Query().Get("title"); title != "" {
+		//单项作业的分数统计，没有该作业时返回404
+		scores := store.ScoresByTitle(title)
+		if len(scores) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		stats = summarize(scores)
+	} else {
+		stats = store.Stats()
+	}
+
+	data, err := studentsHandler{}.toJSON(stats)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		log.Println(err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
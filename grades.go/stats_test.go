@@ -0,0 +1,95 @@
+package grades
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStatsCachedUntilGradeAdded(t *testing.T) {
+	withStudents(t, Students{
+		{ID: 1, Grades: []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: 80}}},
+		{ID: 2, Grades: []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: 60}}},
+	})
+
+	first := store.Stats()
+	if first.Count != 2 || first.Mean != 70 {
+		t.Fatalf("Stats() = %+v, want 2 students with mean 70", first)
+	}
+
+	// 绕过store直接修改数据，缓存有效时读取不会重新计算
+	store.students[1].Grades[0].Score = 100
+	if cached := store.Stats(); cached != first {
+		t.Fatalf("repeated Stats() = %+v, want the cached %+v", cached, first)
+	}
+
+	// 添加成绩使缓存失效，之后的读取反映全部数据
+	if err := store.AddGrade(1, Grade{Title: "Quiz 2", Type: GradeQuiz, Score: 100}); err != nil {
+		t.Fatal(err)
+	}
+	if fresh := store.Stats(); fresh.Mean != 95 || fresh.Max != 100 || fresh.Min != 90 {
+		t.Fatalf("Stats() after AddGrade = %+v, want mean 95, min 90, max 100", fresh)
+	}
+}
+
+func TestSummarizeMedian(t *testing.T) {
+	if got := summarize([]float32{90, 70, 80}); got.Median != 80 {
+		t.Errorf("median of odd count = %v, want 80", got.Median)
+	}
+	if got := summarize([]float32{90, 60, 80, 70}); got.Median != 75 {
+		t.Errorf("median of even count = %v, want 75", got.Median)
+	}
+	if got := summarize(nil); got != (ClassStats{}) {
+		t.Errorf("summarize(nil) = %+v, want zero stats", got)
+	}
+}
+
+// getStats 请求target并解码返回的统计
+func getStats(t *testing.T, target string) ClassStats {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %v status = %v, want 200", target, rec.Code)
+	}
+	var stats ClassStats
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestStatsForTitleOverMockData(t *testing.T) {
+	// 模拟数据中两名学生的Quiz 1都是85分，Quiz 4都是100分
+	want := ClassStats{Count: 2, Mean: 85, Median: 85, Min: 85, Max: 85}
+	if got := getStats(t, "/stats?title=Quiz%201"); got != want {
+		t.Errorf("Quiz 1 stats = %+v, want %+v", got, want)
+	}
+	want = ClassStats{Count: 2, Mean: 100, Median: 100, Min: 100, Max: 100}
+	if got := getStats(t, "/stats?title=Quiz%204"); got != want {
+		t.Errorf("Quiz 4 stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestStatsForTitleWithEvenCount(t *testing.T) {
+	withStudents(t, Students{
+		{ID: 1, Grades: []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: 90}}},
+		{ID: 2, Grades: []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: 60}, {Title: "Quiz 2", Type: GradeQuiz, Score: 10}}},
+		{ID: 3, Grades: []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: 70}}},
+		{ID: 4, Grades: []Grade{{Title: "Quiz 1", Type: GradeQuiz, Score: 80}}},
+	})
+
+	want := ClassStats{Count: 4, Mean: 75, Median: 75, Min: 60, Max: 90}
+	if got := getStats(t, "/stats?title=Quiz%201"); got != want {
+		t.Errorf("Quiz 1 stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestStatsForUnknownTitleIsNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	gradesMux().ServeHTTP(rec, httptest.NewRequest("GET", "/stats?title=Final", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %v, want 404", rec.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package grades
+
+import (
+	"strings"
+	"sync"
+)
+
+// studentStore 保存所有学生及其成绩，处理函数并发访问时由mu保护
+// 读取方法返回副本，调用方可以在锁外安全地使用返回值
+type studentStore struct {
+	mu       sync.RWMutex
+	students Students
+	// stats 缓存全班统计，由mu保护
+	stats statsCache
+	// path 是持久化文件的路径，为空时只保存在内存中
+	path string
+}
+
+var store = new(studentStore)
+
+// cloneStudent 返回学生的深拷贝，成绩切片不与存储共享
+func cloneStudent(s Student) Student {
+	s.Grades = append([]Grade(nil), s.Grades...)
+	return s
+}
+
+// GetAll 返回所有学生的副本
+func (st *studentStore) GetAll() Students {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	result := make(Students, len(st.students))
+	for i, s := range st.students {
+		result[i] = cloneStudent(s)
+	}
+	return result
+}
+
+// GetByID 返回指定学生的副本
+func (st *studentStore) GetByID(id int) (Student, error) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	s, err := st.students.GetByID(id)
+	if err != nil {
+		return Student{}, err
+	}
+	return cloneStudent(*s), nil
+}
+
+// Search 返回名或姓包含name的学生副本，不区分大小写
+func (st *studentStore) Search(name string) Students {
+	name = strings.ToLower(name)
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	result := make(Students, 0)
+	for _, s := range st.students {
+		if strings.Contains(strings.ToLower(s.FirstName), name) ||
+			strings.Contains(strings.ToLower(s.LastName), name) {
+			result = append(result, cloneStudent(s))
+		}
+	}
+	return result
+}
+
+// ScoresByTitle 返回所有学生中标题为title的成绩分数
+func (st *studentStore) ScoresByTitle(title string) []float32 {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	var scores []float32
+	for _, s := range st.students {
+		for _, g := range s.Grades {
+			if g.Title == title {
+				scores = append(scores, g.Score)
+			}
+		}
+	}
+	return scores
+}
+
+// AddGrade 为指定学生追加一条成绩
+func (st *studentStore) AddGrade(id int, g Grade) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, err := st.students.GetByID(id)
+	if err != nil {
+		return err
+	}
+	s.Grades = append(s.Grades, g)
+	st.stats.invalidate()
+	st.saveLocked()
+	return nil
+}
+
+// MoveGrade 把成绩从一个学生移动到另一个学生，失败时不做任何修改
+func (st *studentStore) MoveGrade(m GradeMove) (Grade, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	g, err := st.students.moveGrade(m)
+	if err != nil {
+		return Grade{}, err
+	}
+	st.stats.invalidate()
+	st.saveLocked()
+	return g, nil
+}
+
+// Stats 返回全班统计，缓存有效时只需读锁
+func (st *studentStore) Stats() ClassStats {
+	st.mu.RLock()
+	if st.stats.valid {
+		defer st.mu.RUnlock()
+		return st.stats.stats
+	}
+	st.mu.RUnlock()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.stats.get(st.students)
+}
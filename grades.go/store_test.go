@@ -0,0 +1,60 @@
+package grades
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestStoreConcurrentReadsAndAppends(t *testing.T) {
+	st := &studentStore{students: Students{{ID: 1}, {ID: 2}}}
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := st.AddGrade(1+i%2, Grade{Title: "Quiz", Type: GradeQuiz, Score: 60}); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for _, s := range st.GetAll() {
+				_ = len(s.Grades)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := st.GetByID(2); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s1, _ := st.GetByID(1)
+	s2, _ := st.GetByID(2)
+	if got := len(s1.Grades) + len(s2.Grades); got != n {
+		t.Errorf("got %v grades, want %v", got, n)
+	}
+}
+
+func TestStoreReturnsCopies(t *testing.T) {
+	st := &studentStore{students: Students{{ID: 1, Grades: []Grade{{Title: "Quiz", Score: 60}}}}}
+
+	s, _ := st.GetByID(1)
+	s.Grades[0].Score = 0
+	all := st.GetAll()
+	all[0].Grades[0].Score = 0
+
+	if s, _ := st.GetByID(1); s.Grades[0].Score != 60 {
+		t.Errorf("score = %v, the store was mutated through a returned copy", s.Grades[0].Score)
+	}
+}
+
+func TestMockDataLoadedIntoStore(t *testing.T) {
+	if len(store.GetAll()) == 0 {
+		t.Error("the store holds no students after init")
+	}
+}
@@ -0,0 +1,117 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	stlog "log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 缓冲写入的刷新条件: 距上次刷新满FlushInterval，或缓冲区达到FlushSize字节
+// 底层写入持续失败时缓冲区最多保留MaxBufferedBytes字节，超出时丢弃最早的日志
+const (
+	FlushInterval    = 50 * time.Millisecond
+	FlushSize        = 64 * 1024
+	MaxBufferedBytes = 16 * FlushSize
+)
+
+// bufferedLog 在内存中累积日志，批量写入底层写入器
+// 避免每条日志都打开、写入、关闭一次文件，在高负载下大幅提高吞吐量
+type bufferedLog struct {
+	// dst 是底层写入器，通常是fileLog
+	dst io.Writer
+
+	// buf 是尚未写入dst的日志
+	buf bytes.Buffer
+
+	// mu 保护buf，并保证对dst的写入按顺序进行
+	mu sync.Mutex
+
+	// dropped 是底层写入持续失败、缓冲区超过MaxBufferedBytes时丢弃的日志行数
+	dropped atomic.Uint64
+
+	// done 关闭时定时刷新的goroutine退出，closeOnce保证只关闭一次
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newBufferedLog 创建缓冲写入器，并启动定时刷新的goroutine
+// 参数:
+// - dst: 底层写入器
+func newBufferedLog(dst io.Writer) *bufferedLog {
+	b := &bufferedLog{dst: dst, done: make(chan struct{})}
+	go b.flushLoop()
+	return b
+}
+
+// Write 实现io.Writer接口，将数据追加到缓冲区
+// 缓冲区达到FlushSize时立即刷新；刷新失败且缓冲区超过MaxBufferedBytes时，
+// 按整行丢弃最早的日志，底层写入器故障期间内存占用不会无限增长
+func (b *bufferedLog) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, _ := b.buf.Write(data)
+	if b.buf.Len() < FlushSize {
+		return n, nil
+	}
+	err := b.flushLocked()
+	if b.buf.Len() > MaxBufferedBytes {
+		b.dropOldestLocked(b.buf.Len() - MaxBufferedBytes)
+	}
+	return n, err
+}
+
+// dropOldestLocked 从缓冲区开头丢弃至少n字节，丢弃到整行结束，调用方需持有mu
+func (b *bufferedLog) dropOldestLocked(n int) {
+	data := b.buf.Bytes()
+	cut := len(data)
+	if i := bytes.IndexByte(data[n:], '\n'); i >= 0 {
+		cut = n + i + 1
+	}
+	b.dropped.Add(uint64(bytes.Count(data[:cut], []byte("\n"))))
+	b.buf.Next(cut)
+}
+
+// Flush 将缓冲区中的日志全部写入底层写入器
+func (b *bufferedLog) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.flushLocked()
+}
+
+// flushLocked 执行刷新，调用方需持有mu
+// 写入失败时保留缓冲区内容，下次刷新时重试
+func (b *bufferedLog) flushLocked() error {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	if _, err := b.dst.Write(b.buf.Bytes()); err != nil {
+		return err
+	}
+	b.buf.Reset()
+	return nil
+}
+
+// flushLoop 每隔FlushInterval刷新一次，直到Close被调用
+func (b *bufferedLog) flushLoop() {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.Flush(); err != nil {
+				stlog.Println(err)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// Close 停止定时刷新并写入缓冲区中剩余的日志
+func (b *bufferedLog) Close() error {
+	b.closeOnce.Do(func() { close(b.done) })
+	return b.Flush()
+}
@@ -0,0 +1,138 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBufferedLogKeepsEveryLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	b := newBufferedLog(fileLog(path))
+
+	// 多个goroutine并发写入，既触发按大小刷新也触发定时刷新
+	const writers, lines = 8, 2000
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < lines; i++ {
+				fmt.Fprintf(b, "writer %d line %d\n", w, i)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		seen[sc.Text()] = true
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < lines; i++ {
+			if line := fmt.Sprintf("writer %d line %d", w, i); !seen[line] {
+				t.Fatalf("line %q was dropped", line)
+			}
+		}
+	}
+	if len(seen) != writers*lines {
+		t.Fatalf("file has %d distinct lines, want %d", len(seen), writers*lines)
+	}
+}
+
+// flakyWriter 在failing为true时写入失败，否则把数据追加到buf
+type flakyWriter struct {
+	failing atomic.Bool
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (fw *flakyWriter) Write(data []byte) (int, error) {
+	if fw.failing.Load() {
+		return 0, errors.New("disk full")
+	}
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return fw.buf.Write(data)
+}
+
+func TestBufferedLogBoundedWhileDestinationFails(t *testing.T) {
+	dst := new(flakyWriter)
+	dst.failing.Store(true)
+	b := newBufferedLog(dst)
+
+	const total = 200000
+	for i := 0; i < total; i++ {
+		fmt.Fprintf(b, "line %06d\n", i)
+	}
+	b.mu.Lock()
+	size := b.buf.Len()
+	b.mu.Unlock()
+	if size > MaxBufferedBytes {
+		t.Fatalf("buffer holds %d bytes while the destination fails, want at most %d", size, MaxBufferedBytes)
+	}
+	dropped := b.dropped.Load()
+	if dropped == 0 {
+		t.Fatal("no lines counted as dropped")
+	}
+
+	// 底层写入恢复后，保留下来的最新日志全部写出
+	dst.failing.Store(false)
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+	written := bytes.Count(dst.buf.Bytes(), []byte("\n"))
+	if uint64(written)+dropped != total {
+		t.Fatalf("written %d + dropped %d lines, want %d", written, dropped, total)
+	}
+	if want := fmt.Sprintf("line %06d\n", total-1); !bytes.HasSuffix(dst.buf.Bytes(), []byte(want)) {
+		t.Fatal("newest line was not kept")
+	}
+	if !bytes.HasPrefix(dst.buf.Bytes(), []byte("line ")) {
+		t.Fatal("a partial line was left at the start of the buffer")
+	}
+}
+
+// benchLine 是基准测试写入的一行日志
+var benchLine = []byte("GradingService: student 42 received grade 95 on Quiz 1\n")
+
+func BenchmarkFileLogPerWrite(b *testing.B) {
+	fl := fileLog(filepath.Join(b.TempDir(), "app.log"))
+	b.SetBytes(int64(len(benchLine)))
+	for i := 0; i < b.N; i++ {
+		if _, err := fl.Write(benchLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBufferedLog(b *testing.B) {
+	bl := newBufferedLog(fileLog(filepath.Join(b.TempDir(), "app.log")))
+	b.SetBytes(int64(len(benchLine)))
+	for i := 0; i < b.N; i++ {
+		if _, err := bl.Write(benchLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := bl.Close(); err != nil {
+		b.Fatal(err)
+	}
+}
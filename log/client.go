@@ -3,9 +3,16 @@ package log
 import (
 	"My_mimiDistributed/registry"
 	"bytes"
+	"context"
 	"fmt"
+	"io"
 	stlog "log"
 	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SetClientLogger 设置客户端日志记录器
@@ -22,43 +29,355 @@ func SetClientLogger(serviceURL string, clientService registry.ServiceName) {
 	// 清除默认标志(时间日期等)，因为日志服务会添加这些信息
 	stlog.SetFlags(0)
 	// 将输出重定向到clientLogger，它会将日志发送到远程服务
-	stlog.SetOutput(&clientLogger{url: serviceURL})
+	// 替换已有的客户端时，旧客户端在后台发送完队列中的日志，
+	// 旧日志服务没有响应时也不会阻塞调用方(通常是更新通知的处理器)
+	cl := newClientLogger(serviceURL, DefaultClientQueueSize)
+	stlog.SetOutput(cl)
+	if old := client.Swap(cl); old != nil {
+		go old.Close()
+	}
+}
+
+// WatchProvider 使客户端日志始终发送到一个可用的日志服务
+// 在service.Start之前或之后调用均可:
+// 已发现日志服务时立即调用SetClientLogger，
+// 之后日志服务上线、URL变化或当前实例被移除时自动切换到另一个实例，
+// 没有可用实例时恢复输出到标准错误，直到日志服务再次上线
+// 参数:
+// - clientService: 客户端服务的名称，用于标识日志来源
+func WatchProvider(clientService registry.ServiceName) {
+	var mu sync.Mutex
+	follow := func(removed []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		// 当前实例仍然可用时不切换，避免无谓地重建客户端
+		if cl := client.Load(); cl != nil && !slices.Contains(removed, cl.url) {
+			return
+		}
+		url, err := registry.GetProvider(registry.LogService)
+		if err != nil {
+			resetClientLogger()
+			return
+		}
+		SetClientLogger(url, clientService)
+	}
+	registry.OnUpdate(registry.LogService, func(_, removed []string) {
+		follow(removed)
+	})
+	follow(nil)
+}
+
+// DefaultPollInterval 是PollProvider查询注册中心的默认间隔
+const DefaultPollInterval = 10 * time.Second
+
+// PollProvider 通过定期查询注册中心的GET /services发现日志服务
+// 用于无法接收注册中心推送(没有挂载更新处理器)的客户端，能接收推送时应使用WatchProvider
+// 当前实例仍在注册表中时保持不变，否则切换到注册表中的另一个实例，
+// 没有可用实例时恢复输出到标准错误
+// 此函数会阻塞直到ctx被取消，通常在单独的goroutine中运行
+// 参数:
+// - ctx: 控制轮询生命周期的上下文
+// - clientService: 客户端服务的名称，用于标识日志来源
+// - interval: 轮询间隔，小于等于0时使用DefaultPollInterval
+func PollProvider(ctx context.Context, clientService registry.ServiceName, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pollOnce(ctx, clientService)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollOnce 查询一次注册表并据此更新日志客户端的目标
+// 注册中心不可达时保持当前配置，下一次轮询再试
+func pollOnce(ctx context.Context, clientService registry.ServiceName) {
+	regs, err := registry.ListRegistrations(ctx)
+	if err != nil {
+		return
+	}
+	var urls []string
+	for _, r := range regs {
+		if r.ServiceName == registry.LogService {
+			urls = append(urls, r.ServiceURL)
+		}
+	}
+	if cl := client.Load(); cl != nil && slices.Contains(urls, cl.url) {
+		return
+	}
+	if len(urls) == 0 {
+		resetClientLogger()
+		return
+	}
+	SetClientLogger(urls[0], clientService)
+}
+
+// resetClientLogger 停止向日志服务发送日志，恢复标准日志输出到标准错误
+// 队列中尚未发送的日志仍会尝试发送，失败时写入备用输出
+func resetClientLogger() {
+	old := client.Swap(nil)
+	if old == nil {
+		return
+	}
+	stlog.SetOutput(os.Stderr)
+	stlog.SetFlags(stlog.LstdFlags)
+	go old.Close()
 }
 
+// FlushClient 等待客户端队列中的日志全部发送完毕
+func FlushClient() {
+	if cl := client.Load(); cl != nil {
+		cl.Flush()
+	}
+}
+
+// CloseClient 发送完客户端队列中的日志并停止后台发送，服务关闭前调用
+func CloseClient() {
+	if cl := client.Load(); cl != nil {
+		cl.Close()
+	}
+}
+
+// DroppedClientLogs 返回因客户端队列已满而被丢弃的日志条数
+func DroppedClientLogs() uint64 {
+	cl := client.Load()
+	if cl == nil {
+		return 0
+	}
+	return cl.dropped.Load()
+}
+
+// client 是SetClientLogger设置的日志客户端，供Debug、Info等函数使用
+// 日志服务变化时可能在更新通知的goroutine中被替换，因此使用原子指针
+var client atomic.Pointer[clientLogger]
+
+// Debug 以LevelDebug级别发送日志，参数处理方式与log.Print相同
+func Debug(v ...any) { output(LevelDebug, v) }
+
+// Info 以LevelInfo级别发送日志，参数处理方式与log.Print相同
+func Info(v ...any) { output(LevelInfo, v) }
+
+// Warn 以LevelWarn级别发送日志，参数处理方式与log.Print相同
+func Warn(v ...any) { output(LevelWarn, v) }
+
+// Error 以LevelError级别发送日志，参数处理方式与log.Print相同
+func Error(v ...any) { output(LevelError, v) }
+
+// output 格式化日志并按级别发送到日志服务
+// 未调用SetClientLogger时输出到标准日志
+func output(level Level, v []any) {
+	cl := client.Load()
+	if cl == nil {
+		stlog.Print(v...)
+		return
+	}
+	// 设置了采样率的级别只有被采样选中的日志才会发送
+	if !sample(level) {
+		return
+	}
+	cl.enqueue([]byte(stlog.Prefix()+fmt.Sprint(v...)), level)
+}
+
+// DefaultClientQueueSize 是客户端日志队列的默认容量
+const DefaultClientQueueSize = 1024
+
 // clientLogger 实现io.Writer接口，用于客户端日志记录
 // 它是标准日志库和远程日志服务之间的桥梁
-// 当服务调用log.Print等函数时，日志内容会通过此结构发送到中央日志服务
+// 当服务调用log.Print等函数时，日志内容进入队列，由后台goroutine发送到中央日志服务，
+// 调用方不会因为网络往返或日志服务变慢而阻塞
 type clientLogger struct {
 	// 日志服务的URL，如http://localhost:4000
 	url string
+
+	// host 是本机主机名，随每条日志发送
+	host string
+
+	// queue 是待发送的日志队列
+	queue chan clientMessage
+
+	// pending 记录已入队但尚未发送完成的日志数，idle在其归零时广播，供Flush等待
+	pending int
+	idle    *sync.Cond
+
+	// dropped 是因队列已满而被丢弃的日志条数
+	dropped atomic.Uint64
+
+	// mu 保护pending和closed，避免向已关闭的队列发送
+	mu     sync.Mutex
+	closed bool
+}
+
+// clientMessage 是队列中的一条日志
+type clientMessage struct {
+	data  []byte
+	level Level
+	// at 是日志入队的时间，即事件在客户端发生的时间
+	at time.Time
+}
+
+// newClientLogger 创建日志客户端并启动后台发送goroutine
+// 参数:
+// - url: 日志服务的URL
+// - queueSize: 队列容量
+func newClientLogger(url string, queueSize int) *clientLogger {
+	host, _ := os.Hostname()
+	cl := &clientLogger{url: url, host: host, queue: make(chan clientMessage, queueSize)}
+	cl.idle = sync.NewCond(&cl.mu)
+	go cl.run()
+	return cl
 }
 
-// Write 实现io.Writer接口，发送日志到远程日志服务
+// Write 实现io.Writer接口，将日志放入发送队列后立即返回
 // 当客户端调用log.Print等函数时，最终会调用此方法
-// 业务流程:
-// 1. 将日志数据包装为HTTP请求
-// 2. 发送POST请求到日志服务
-// 3. 验证响应并返回结果
+// 队列已满时丢弃该条日志并计数，而不是阻塞调用方
+// 标准库日志没有级别，可能包含错误，因此不参与采样(见SetSampleRate)
 // 参数:
 // - data: 要记录的日志数据
 // 返回:
 // - int: 写入的字节数
-// - error: 写入过程中的错误
-func (cl clientLogger) Write(data []byte) (int, error) {
-	// 创建请求体缓冲区
-	b := bytes.NewBuffer(data)
+// - error: 始终为nil
+func (cl *clientLogger) Write(data []byte) (int, error) {
+	// log包会复用data的底层数组，入队前需要复制
+	cl.enqueue(bytes.Clone(data), LevelInfo)
+	return len(data), nil
+}
+
+// enqueue 将一条日志放入队列，队列已满或客户端已关闭时丢弃
+func (cl *clientLogger) enqueue(data []byte, level Level) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.closed {
+		cl.dropped.Add(1)
+		return
+	}
+	select {
+	case cl.queue <- clientMessage{data: data, level: level, at: time.Now()}:
+		cl.pending++
+	default:
+		cl.dropped.Add(1)
+	}
+}
+
+// 远程发送失败时的重试策略: 共尝试3次，依次等待100ms、200ms
+const (
+	SendAttempts  = 3
+	SendBaseDelay = 100 * time.Millisecond
+)
+
+// fallback 是日志服务不可用时日志的去处，默认为标准错误
+var (
+	fallback   io.Writer = os.Stderr
+	fallbackMu sync.Mutex
+)
+
+// SetFallbackWriter 设置远程发送最终失败时日志的写入目标
+// 参数:
+// - w: 备用写入器，传入nil时恢复为标准错误
+func SetFallbackWriter(w io.Writer) {
+	if w == nil {
+		w = os.Stderr
+	}
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+	fallback = w
+}
+
+// run 依次发送队列中的日志，直到队列被关闭
+func (cl *clientLogger) run() {
+	for msg := range cl.queue {
+		cl.deliver(msg)
+		cl.mu.Lock()
+		cl.pending--
+		if cl.pending == 0 {
+			cl.idle.Broadcast()
+		}
+		cl.mu.Unlock()
+	}
+}
+
+// deliver 发送一条日志，失败时按指数退避重试
+// 重试后仍然失败时写入备用写入器，保证日志不会丢失
+func (cl *clientLogger) deliver(msg clientMessage) {
+	delay := SendBaseDelay
+	var err error
+	for attempt := 1; attempt <= SendAttempts; attempt++ {
+		if err = cl.send(context.Background(), msg); err == nil {
+			return
+		}
+		if attempt < SendAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+
+	// 报告失败，之后的服务发现优先选择其他日志服务实例
+	registry.ReportFailure(registry.LogService, cl.url)
+
+	fallbackMu.Lock()
+	defer fallbackMu.Unlock()
+	fmt.Fprintf(fallback, "log service unavailable (%v): %s", err, msg.data)
+	if !bytes.HasSuffix(msg.data, []byte("\n")) {
+		fmt.Fprintln(fallback)
+	}
+}
+
+// Flush 等待队列中已有的日志全部发送完毕
+func (cl *clientLogger) Flush() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	for cl.pending > 0 {
+		cl.idle.Wait()
+	}
+}
+
+// Close 停止接收新日志，发送完队列中剩余的日志后返回
+func (cl *clientLogger) Close() {
+	cl.mu.Lock()
+	if !cl.closed {
+		cl.closed = true
+		close(cl.queue)
+	}
+	cl.mu.Unlock()
+	cl.Flush()
+}
+
+// send 发送一条日志到日志服务
+// 级别、产生时间和主机名通过请求头传递
+// 参数:
+// - ctx: 请求上下文，取消时请求随之中止
+// - msg: 要发送的日志
+// 返回:
+// - error: 发送过程中的错误
+func (cl *clientLogger) send(ctx context.Context, msg clientMessage) error {
+	// 创建请求，请求体为日志内容
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cl.url+"/log", bytes.NewBuffer(msg.data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set(LevelHeader, msg.level.String())
+	req.Header.Set(TimestampHeader, msg.at.Format(time.RFC3339Nano))
+	if cl.host != "" {
+		req.Header.Set(HostHeader, cl.host)
+	}
+
 	// 发送POST请求到日志服务的/log端点
-	res, err := http.Post(cl.url+"/log", "text/plain", b)
+	res, err := registry.HTTPClient().Do(req)
 	if err != nil {
 		// 网络错误或日志服务不可用时返回错误
-		return 0, err
+		return err
 	}
+	res.Body.Close()
 
 	// 检查响应状态码，确保日志成功记录
 	if res.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("failed to send log message, status: %v", res.StatusCode)
+		return fmt.Errorf("failed to send log message, status: %v", res.StatusCode)
 	}
-
-	// 返回写入的数据长度和nil错误表示成功
-	return len(data), nil
+	return nil
 }
@@ -0,0 +1,239 @@
+package log
+
+import (
+	"My_mimiDistributed/registry"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRegistry 启动一个只响应GET /services的注册中心，返回regs中当前的注册信息
+func fakeRegistry(t *testing.T, regs *atomic.Value) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(regs.Load())
+	}))
+	registry.SetRegistryURL(srv.URL)
+	t.Cleanup(func() {
+		registry.SetRegistryURL("")
+		srv.Close()
+	})
+}
+
+// waitFor 在超时前反复检查cond，直到其返回true
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %v", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestPollProviderDiscoversLogService(t *testing.T) {
+	var regs atomic.Value
+	regs.Store([]registry.Registration{})
+	fakeRegistry(t, &regs)
+	t.Cleanup(resetClientLogger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go PollProvider(ctx, "PollTest", 10*time.Millisecond)
+
+	// 日志服务尚未注册时不配置客户端
+	time.Sleep(30 * time.Millisecond)
+	if cl := client.Load(); cl != nil {
+		t.Fatalf("client pointed at %v before any log service registered", cl.url)
+	}
+
+	const logURL = "http://log.example:4000"
+	regs.Store([]registry.Registration{{ServiceName: registry.LogService, ServiceURL: logURL}})
+	waitFor(t, "client to target the polled log service", func() bool {
+		cl := client.Load()
+		return cl != nil && cl.url == logURL
+	})
+
+	// 日志服务下线后恢复到标准错误
+	regs.Store([]registry.Registration{})
+	waitFor(t, "client to be reset", func() bool { return client.Load() == nil })
+}
+
+func TestSetClientLoggerDoesNotWaitForOldClient(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(hung.Close)
+	t.Cleanup(func() { close(release) })
+	t.Cleanup(resetClientLogger)
+
+	SetClientLogger(hung.URL, "SwapTest")
+	Error("stuck on the unresponsive log service")
+
+	done := make(chan struct{})
+	go func() {
+		SetClientLogger("http://log.example:4000", "SwapTest")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SetClientLogger blocked on the old client's queue")
+	}
+}
+
+func TestClientLoggerNeverBlocksWhenQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	var received atomic.Int64
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		received.Add(1)
+	}))
+	t.Cleanup(slow.Close)
+
+	const queueSize, messages = 4, 1000
+	cl := newClientLogger(slow.URL, queueSize)
+	start := time.Now()
+	for i := 0; i < messages; i++ {
+		cl.Write([]byte("overflow\n"))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("%d writes to a stuck log service took %v, want them to return immediately", messages, elapsed)
+	}
+	dropped := cl.dropped.Load()
+	if dropped == 0 || dropped > messages-queueSize {
+		t.Errorf("dropped %d of %d messages with a queue of %d", dropped, messages, queueSize)
+	}
+
+	// 日志服务恢复后，Close发送完队列中剩余的日志
+	close(release)
+	cl.Close()
+	if got := received.Load() + int64(dropped); got != messages {
+		t.Errorf("delivered %d and dropped %d of %d messages", received.Load(), dropped, messages)
+	}
+	if cl.Write([]byte("after close\n")); cl.dropped.Load() != dropped+1 {
+		t.Error("write after Close was not counted as dropped")
+	}
+}
+
+// withFallback 在测试期间把备用写入器替换为返回的缓冲区
+func withFallback(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	SetFallbackWriter(buf)
+	t.Cleanup(func() { SetFallbackWriter(nil) })
+	return buf
+}
+
+func TestFailedDeliveryRetriesThenFallsBack(t *testing.T) {
+	var attempts atomic.Int64
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+	fallbackBuf := withFallback(t)
+
+	cl := newClientLogger(failing.URL, 10)
+	n, err := cl.Write([]byte("must not be lost\n"))
+	if err != nil || n != len("must not be lost\n") {
+		t.Fatalf("Write = %d, %v; want the full length and no error", n, err)
+	}
+	cl.Close()
+
+	if got := attempts.Load(); got != SendAttempts {
+		t.Errorf("log service received %d attempts, want %d", got, SendAttempts)
+	}
+	if !strings.Contains(fallbackBuf.String(), "must not be lost") {
+		t.Errorf("fallback writer got %q, want the undelivered message", fallbackBuf.String())
+	}
+}
+
+func TestDeliveryRecoversOnRetry(t *testing.T) {
+	var attempts atomic.Int64
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(flaky.Close)
+	fallbackBuf := withFallback(t)
+
+	cl := newClientLogger(flaky.URL, 10)
+	cl.Write([]byte("delivered on the second try\n"))
+	cl.Close()
+
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("log service received %d attempts, want 2", got)
+	}
+	if fallbackBuf.Len() != 0 {
+		t.Errorf("fallback writer got %q, want nothing after a successful retry", fallbackBuf.String())
+	}
+}
+
+func TestSendCanceledMidFlight(t *testing.T) {
+	entered := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 读完请求体后服务器才会发现客户端断开连接
+		io.Copy(io.Discard, r.Body)
+		close(entered)
+		<-r.Context().Done()
+	}))
+	t.Cleanup(hung.Close)
+	cl := newClientLogger(hung.URL, 1)
+	defer cl.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-entered
+		cancel()
+	}()
+	err := cl.send(ctx, clientMessage{data: []byte("hello\n"), level: LevelInfo, at: time.Now()})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("send = %v, want context.Canceled", err)
+	}
+}
+
+// postLogPatch 通过更新处理器向本进程推送一个日志服务的变更
+func postLogPatch(t *testing.T, field, url string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	if err := registry.RegisterUpdateHandler(mux, registry.Registration{ServiceUpdateURL: "http://test/services"}); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"` + field + `":[{"Name":"LogService","URL":"` + url + `"}]}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update handler returned %d for %s", rec.Code, body)
+	}
+}
+
+func TestWatchProviderFollowsLaterLogService(t *testing.T) {
+	t.Cleanup(resetClientLogger)
+	WatchProvider("WatchTest")
+	if cl := client.Load(); cl != nil {
+		t.Fatalf("client pointed at %v before any log service registered", cl.url)
+	}
+
+	const logURL = "http://log.example:4100"
+	postLogPatch(t, "Added", logURL)
+	if cl := client.Load(); cl == nil || cl.url != logURL {
+		t.Fatalf("client did not switch to the log service registered after startup")
+	}
+
+	postLogPatch(t, "Removed", logURL)
+	if cl := client.Load(); cl != nil {
+		t.Errorf("client still points at %v after the log service was removed", cl.url)
+	}
+}
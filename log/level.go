@@ -0,0 +1,72 @@
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// Level 是日志级别，数值越大越重要
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// 客户端随日志发送的请求头
+const (
+	// LevelHeader 是日志级别，未设置时按LevelInfo处理
+	LevelHeader = "X-Log-Level"
+
+	// TimestampHeader 是日志在客户端产生的时间，RFC3339Nano格式
+	TimestampHeader = "X-Log-Timestamp"
+
+	// HostHeader 是发送日志的客户端主机名
+	HostHeader = "X-Log-Host"
+)
+
+// String 返回级别的名称，也是LevelHeader中使用的值
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Level(%d)", int(l))
+	}
+}
+
+// ParseLevel 解析级别名称，不区分大小写，空字符串视为LevelInfo
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "", "INFO":
+		return LevelInfo, nil
+	case "WARN":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+// minLevel 是日志服务接受的最低级别，低于此级别的日志被丢弃
+var minLevel atomic.Int64
+
+// SetMinLevel 设置日志服务接受的最低级别，默认为LevelDebug(全部接受)
+// 可用于集中屏蔽某些服务过多的调试日志
+// 参数:
+// - level: 最低级别
+func SetMinLevel(level Level) {
+	minLevel.Store(int64(level))
+}
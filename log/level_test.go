@@ -0,0 +1,81 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestServerDropsMessagesBelowMinLevel(t *testing.T) {
+	if err := runAt(t, filepath.Join(t.TempDir(), "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	SetMinLevel(LevelWarn)
+	t.Cleanup(func() { SetMinLevel(LevelDebug) })
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// 客户端按级别发送日志，由服务端统一过滤
+	cl := newClientLogger(srv.URL, 10)
+	saved := client.Swap(cl)
+	t.Cleanup(func() {
+		client.Store(saved)
+		cl.Close()
+	})
+	Debug("level test debug")
+	Info("level test info")
+	Warn("level test warn")
+	Error("level test error")
+	cl.Flush()
+
+	// 其他测试可能设置了标准库日志的前缀，只按内容匹配
+	written := func(msg string) bool {
+		return slices.ContainsFunc(recent.last(RecentLines), func(line string) bool {
+			return strings.Contains(line, msg)
+		})
+	}
+	for _, dropped := range []string{"level test debug", "level test info"} {
+		if written(dropped) {
+			t.Errorf("%q was written although the minimum level is WARN", dropped)
+		}
+	}
+	for _, kept := range []string{"level test warn", "level test error"} {
+		if !written(kept) {
+			t.Errorf("%q was dropped although it is at or above WARN", kept)
+		}
+	}
+}
+
+func TestParseLevelIgnoresCase(t *testing.T) {
+	if got, err := ParseLevel("Warn"); err != nil || got != LevelWarn {
+		t.Errorf("ParseLevel(\"Warn\") = %v, %v; want WARN", got, err)
+	}
+	// 未设置级别的日志按INFO处理
+	if got, err := ParseLevel(""); err != nil || got != LevelInfo {
+		t.Errorf("ParseLevel(\"\") = %v, %v; want INFO", got, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel accepted an unknown level")
+	}
+	if !(LevelDebug < LevelInfo && LevelInfo < LevelWarn && LevelWarn < LevelError) {
+		t.Error("levels are not ordered by importance")
+	}
+}
+
+func TestLogHandlerRejectsUnknownLevel(t *testing.T) {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	req := httptest.NewRequest(http.MethodPost, "/log", nil)
+	req.Header.Set(LevelHeader, "loud")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("POST /log with an unknown level = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,77 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// 日志文件轮转的默认配置: 单个文件10MB，保留5个备份
+const (
+	DefaultMaxBytes   = 10 << 20
+	DefaultMaxBackups = 5
+)
+
+// rotatingLog 包装fileLog，在文件超过maxBytes时进行轮转
+// 当前文件重命名为path.1，已有的path.1重命名为path.2，依此类推，
+// 超出maxBackups的最旧备份被删除
+type rotatingLog struct {
+	// path 是当前日志文件的路径
+	path string
+
+	// maxBytes 是单个日志文件的最大字节数，小于等于0表示不轮转
+	maxBytes int64
+
+	// maxBackups 是保留的备份文件数量
+	maxBackups int
+
+	// mu 保证轮转和写入不会交错进行
+	mu sync.Mutex
+}
+
+// Write 实现io.Writer接口，写入前若文件将超过maxBytes则先轮转
+func (rl *rotatingLog) Write(data []byte) (int, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.maxBytes > 0 {
+		info, err := os.Stat(rl.path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return 0, err
+		}
+		if err == nil && info.Size() > 0 && info.Size()+int64(len(data)) > rl.maxBytes {
+			if err := rl.rotate(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return fileLog(rl.path).Write(data)
+}
+
+// rotate 依次后移备份文件，并将当前文件重命名为第一个备份
+// 调用方需持有mu
+func (rl *rotatingLog) rotate() error {
+	if rl.maxBackups < 1 {
+		// 不保留备份，直接清空当前文件
+		return os.Truncate(rl.path, 0)
+	}
+	// 删除最旧的备份，为后移腾出位置
+	oldest := backupName(rl.path, rl.maxBackups)
+	if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := rl.maxBackups - 1; i >= 1; i-- {
+		err := os.Rename(backupName(rl.path, i), backupName(rl.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(rl.path, backupName(rl.path, 1))
+}
+
+// backupName 返回第n个备份文件的路径，例如distributed.log.1
+func backupName(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
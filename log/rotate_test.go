@@ -0,0 +1,116 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fileSize 返回path的大小，文件不存在时测试失败
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Size()
+}
+
+func TestRotationCreatesBackupAndTruncatesLiveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "distributed.log")
+	rl := &rotatingLog{path: path, maxBytes: 100, maxBackups: 2}
+	line := strings.Repeat("x", 39) + "\n"
+
+	for i := 0; i < 2; i++ {
+		if _, err := rl.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(backupName(path, 1)); err == nil {
+		t.Fatal("rotated before the file reached maxBytes")
+	}
+
+	// 第三行会使文件超过100字节，写入前先轮转
+	if _, err := rl.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	if size := fileSize(t, backupName(path, 1)); size != 80 {
+		t.Errorf("backup holds %d bytes, want the 80 bytes written before rotation", size)
+	}
+	if size := fileSize(t, path); size != 40 {
+		t.Errorf("live file holds %d bytes after rotation, want only the new line", size)
+	}
+}
+
+func TestRotationKeepsAtMostMaxBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "distributed.log")
+	rl := &rotatingLog{path: path, maxBytes: 10, maxBackups: 2}
+
+	// 每次写入都超过maxBytes，除第一次外都会轮转
+	for _, msg := range []string{"first line\n", "second line\n", "third line\n", "fourth line\n"} {
+		if _, err := rl.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read(path); got != "fourth line\n" {
+		t.Errorf("live file = %q, want the newest line", got)
+	}
+	if got := read(backupName(path, 1)); got != "third line\n" {
+		t.Errorf("backup 1 = %q, want the third line", got)
+	}
+	if got := read(backupName(path, 2)); got != "second line\n" {
+		t.Errorf("backup 2 = %q, want the second line", got)
+	}
+	if _, err := os.Stat(backupName(path, 3)); err == nil {
+		t.Error("a third backup exists, want at most maxBackups")
+	}
+}
+
+func TestRotationConcurrentWritesLoseNothing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "distributed.log")
+	const writers, lines = 8, 50
+	rl := &rotatingLog{path: path, maxBytes: 1000, maxBackups: writers * lines}
+	line := strings.Repeat("y", 99) + "\n"
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				if _, err := rl.Write([]byte(line)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 所有文件加起来正好是写入的全部内容
+	files, err := filepath.Glob(path + "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, f := range files {
+		size := fileSize(t, f)
+		if size > rl.maxBytes {
+			t.Errorf("%v holds %d bytes, more than maxBytes", f, size)
+		}
+		total += size
+	}
+	if want := int64(writers * lines * len(line)); total != want {
+		t.Errorf("files hold %d bytes in total, want %d", total, want)
+	}
+}
@@ -0,0 +1,47 @@
+package log
+
+import "sync/atomic"
+
+// sampleRates 是DEBUG、INFO、WARN各级别的采样率N，即每N条只发送1条
+// 0或1表示全部发送；ERROR级别不参与采样，总是发送
+var sampleRates [LevelError]atomic.Int64
+
+// sampleCounters 是各级别已产生的日志条数，用于决定哪一条被发送
+var sampleCounters [LevelError]atomic.Uint64
+
+// sampledOut 是因采样而未发送的日志条数
+var sampledOut atomic.Uint64
+
+// SetSampleRate 设置本服务发送到日志服务的某一级别日志的采样率
+// 日志量很大的服务可借此避免压垮中央日志服务，设置对本进程内的客户端日志生效
+// 只作用于Debug、Info、Warn等带级别的函数，经标准库log输出的日志总是发送
+// 参数:
+// - level: 日志级别，LevelError总是全部发送，对其设置无效
+// - n: 每n条发送1条(第1、n+1、2n+1...条)，小于等于1表示全部发送
+func SetSampleRate(level Level, n int) {
+	if level < LevelDebug || level >= LevelError {
+		return
+	}
+	sampleRates[level].Store(int64(n))
+}
+
+// sample 判断一条level级别的日志是否应当发送
+func sample(level Level) bool {
+	if level < LevelDebug || level >= LevelError {
+		return true
+	}
+	n := sampleRates[level].Load()
+	if n <= 1 {
+		return true
+	}
+	if (sampleCounters[level].Add(1)-1)%uint64(n) == 0 {
+		return true
+	}
+	sampledOut.Add(1)
+	return false
+}
+
+// SampledClientLogs 返回因采样而未发送到日志服务的日志条数
+func SampledClientLogs() uint64 {
+	return sampledOut.Load()
+}
@@ -0,0 +1,63 @@
+package log
+
+import (
+	"sync"
+	"testing"
+)
+
+// withQueueClient 在测试期间把客户端替换为只入队、不发送的clientLogger
+func withQueueClient(t *testing.T, size int) *clientLogger {
+	t.Helper()
+	cl := &clientLogger{queue: make(chan clientMessage, size)}
+	cl.idle = sync.NewCond(&cl.mu)
+	saved := client.Swap(cl)
+	t.Cleanup(func() { client.Store(saved) })
+	return cl
+}
+
+func TestSamplingKeepsErrors(t *testing.T) {
+	SetSampleRate(LevelInfo, 10)
+	SetSampleRate(LevelError, 10)
+	t.Cleanup(func() {
+		SetSampleRate(LevelInfo, 0)
+		sampleCounters[LevelInfo].Store(0)
+	})
+
+	cl := withQueueClient(t, 1000)
+	for i := 0; i < 100; i++ {
+		Info("info")
+	}
+	for i := 0; i < 20; i++ {
+		Error("error")
+	}
+	for i := 0; i < 5; i++ {
+		Warn("warn")
+	}
+	// 标准库日志没有级别，不参与采样，其中可能包含错误
+	for i := 0; i < 7; i++ {
+		cl.Write([]byte("stdlib line\n"))
+	}
+	close(cl.queue)
+
+	counts := make(map[Level]int)
+	stdlib := 0
+	for msg := range cl.queue {
+		if string(msg.data) == "stdlib line\n" {
+			stdlib++
+			continue
+		}
+		counts[msg.level]++
+	}
+	if counts[LevelInfo] != 10 {
+		t.Errorf("sent %d of 100 INFO messages, want 10", counts[LevelInfo])
+	}
+	if counts[LevelError] != 20 {
+		t.Errorf("sent %d of 20 ERROR messages, want all 20", counts[LevelError])
+	}
+	if counts[LevelWarn] != 5 {
+		t.Errorf("sent %d of 5 unsampled WARN messages, want all 5", counts[LevelWarn])
+	}
+	if stdlib != 7 {
+		t.Errorf("sent %d of 7 standard-library lines, want all 7", stdlib)
+	}
+}
@@ -1,16 +1,79 @@
 package log
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	stlog "log"
+	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+// ReadTimeout 是读取单条日志请求体的最长时间
+const ReadTimeout = 10 * time.Second
+
+// readTimeout 是处理函数实际使用的读取期限，测试时可以缩短
+var readTimeout = ReadTimeout
+
 // 全局日志记录器实例，用于写入日志文件
 // 它由Run函数初始化，并由write函数使用
 var log *stlog.Logger
 
+// buffer 是log的输出目标，批量将日志写入文件，由Close在关闭时刷新
+var buffer *bufferedLog
+
+// RecentLines 是内存中保留的最近日志条数，GET /log最多返回这么多条
+const RecentLines = 1000
+
+// ring 是保存最近日志的环形缓冲区
+// 写满后新日志覆盖最旧的日志，内存占用固定
+type ring struct {
+	lines []string
+	// next 是下一条日志写入的位置
+	next int
+	// full 表示缓冲区已写满过一轮
+	full bool
+	mu   sync.Mutex
+}
+
+// add 追加一条日志
+func (r *ring) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// last 按时间顺序返回最近的n条日志
+func (r *ring) last(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	size := r.next
+	if r.full {
+		size = len(r.lines)
+	}
+	n = min(n, size)
+	result := make([]string, 0, n)
+	for i := r.next - n; i < r.next; i++ {
+		result = append(result, r.lines[(i+len(r.lines))%len(r.lines)])
+	}
+	return result
+}
+
+// recent 保存最近写入的日志，供GET /log查询
+var recent = &ring{lines: make([]string, RecentLines)}
+
 // fileLog 是一个自定义字符串类型，实现了io.Writer接口
 // 用作日志的目标写入器，将日志写入指定的文件路径
 // 在微服务架构中，分离日志记录逻辑是一个良好实践
@@ -18,7 +81,7 @@ type fileLog string
 
 // Write 实现io.Writer接口的方法，将数据写入到文件
 // 每次写入都会打开文件，写入数据后关闭，确保数据被持久化
-// 这种方式虽然有性能开销，但确保了每条日志都被写入磁盘，避免丢失
+// 由bufferedLog批量调用，分摊打开文件的开销
 // 参数:
 // - data: 要写入的字节数据
 // 返回:
@@ -45,32 +108,123 @@ func (fl fileLog) Write(data []byte) (int, error) {
 
 // Run 初始化日志系统
 // 此函数在日志服务启动时被调用，设置日志记录器
+// 由于fileLog每次写入时才打开文件，路径问题会导致之后的每次写入都静默失败，
+// 所以这里在启动时就校验目标路径
 // 业务流程:
-// 1. 创建指向指定文件的日志记录器
-// 2. 设置日志格式和前缀
+// 1. 校验路径非空，创建缺失的父目录
+// 2. 确认日志文件可写
+// 3. 创建指向指定文件的缓冲日志记录器，设置日志格式和前缀
 // 参数:
 // - destination: 日志文件的路径
-func Run(destination string) {
-	// 创建新的日志记录器，使用fileLog作为输出目标
-	// 参数1: io.Writer接口实现，这里是fileLog类型
+// - maxBytes: 单个日志文件的最大字节数，超过后轮转，小于等于0表示不轮转
+// - maxBackups: 轮转时保留的备份文件数量
+// 返回:
+// - error: 路径为空、目录无法创建或文件不可写时返回错误
+func Run(destination string, maxBytes int64, maxBackups int) error {
+	if destination == "" {
+		return errors.New("log destination must not be empty")
+	}
+	destination = filepath.Clean(destination)
+
+	// 创建缺失的父目录
+	if err := os.MkdirAll(filepath.Dir(destination), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	// 预先打开一次文件，确认其可写
+	f, err := os.OpenFile(destination, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("log destination is not writable: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// 创建新的日志记录器，使用缓冲、按大小轮转的fileLog作为输出目标
+	// 参数1: io.Writer接口实现，这里是包装了rotatingLog的bufferedLog
 	// 参数2: 日志前缀，每条日志前都会添加此前缀
 	// 参数3: 标准日志标志，包含时间、日期等信息
-	log = stlog.New(fileLog(destination), "[go] - ", stlog.LstdFlags)
+	buffer = newBufferedLog(&rotatingLog{
+		path:       destination,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	})
+	// 时间由write写入，以便优先使用客户端提供的时间戳
+	log = stlog.New(buffer, "[go] - ", 0)
+	return nil
+}
+
+// Shutdown 在ctx到期前将缓冲区中的日志刷新到文件，并停止定时刷新
+// 可作为service.WithShutdownHook的关闭钩子
+// 参数:
+// - ctx: 控制刷新最长等待时间的上下文
+// 返回:
+// - error: 写入文件过程中的错误，或ctx到期时的ctx.Err()
+func Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() { done <- Close() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 将缓冲区中尚未写入的日志刷新到文件，服务关闭前必须调用
+// 返回:
+// - error: 写入文件过程中的错误
+func Close() error {
+	if buffer == nil {
+		return nil
+	}
+	return buffer.Close()
+}
+
+// DroppedLines 返回因日志文件持续无法写入、缓冲区已满而被丢弃的日志行数
+func DroppedLines() uint64 {
+	if buffer == nil {
+		return 0
+	}
+	return buffer.dropped.Load()
 }
 
 // RegisterHandlers 注册HTTP路由处理函数
 // 这是日志服务的核心，设置HTTP接口用于接收日志请求
-// 在服务启动时被调用，注册/log路径的处理函数
-func RegisterHandlers() {
+// 在服务启动时被调用，在服务的路由器上注册/log路径的处理函数
+func RegisterHandlers(mux *http.ServeMux) {
 	// 注册/log路径的HTTP处理函数
 	// 这是日志服务对外暴露的唯一接口
-	http.HandleFunc("/log", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/log", func(w http.ResponseWriter, r *http.Request) {
 		// 根据HTTP方法类型处理请求
 		switch r.Method {
 		case http.MethodPost: // 只处理POST请求
+			// 设置读取期限，防止客户端缓慢地发送请求体而长期占用处理协程
+			rc := http.NewResponseController(w)
+			if err := rc.SetReadDeadline(time.Now().Add(readTimeout)); err != nil {
+				stlog.Println(err)
+			}
+
+			// 解析日志级别，低于最低级别的日志直接丢弃
+			level, err := ParseLevel(r.Header.Get(LevelHeader))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if int64(level) < minLevel.Load() {
+				return
+			}
+
 			// 读取请求体内容，这是要记录的日志消息
 			msg, err := io.ReadAll(r.Body)
 
+			// 读取超时，返回408错误
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				w.WriteHeader(http.StatusRequestTimeout)
+				return
+			}
+
 			// 错误处理：如果读取出错或内容为空，返回400错误
 			// 日志记录需要有实际内容才有意义
 			if err != nil || len(msg) == 0 {
@@ -78,14 +232,45 @@ func RegisterHandlers() {
 				return
 			}
 
+			// 客户端提供了事件发生的时间时优先使用，否则使用接收时间
+			at := time.Now()
+			if ts := r.Header.Get(TimestampHeader); ts != "" {
+				if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+					at = t
+				}
+			}
+
 			// 将消息写入日志文件
 			// 将字节数据转换为字符串传递给write函数
-			write(string(msg))
+			write(string(msg), at, r.Header.Get(HostHeader))
 
 			// 默认返回200 OK状态码
 
-		default: // 对于非POST请求，返回405方法不允许
-			// 日志服务只接受POST方法，其他方法被拒绝
+		case http.MethodGet: // 查询最近的日志，默认返回100条
+			n := 100
+			if s := r.URL.Query().Get("n"); s != "" {
+				var err error
+				n, err = strconv.Atoi(s)
+				if err != nil || n < 0 {
+					http.Error(w, "n must be a non-negative integer", http.StatusBadRequest)
+					return
+				}
+			}
+			lines := recent.last(n)
+
+			// format=json时返回JSON数组，否则每行一条纯文本
+			if r.URL.Query().Get("format") == "json" {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(lines)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			for _, line := range lines {
+				fmt.Fprintln(w, line)
+			}
+
+		default: // 对于其他请求，返回405方法不允许
+			// 日志服务只接受POST和GET方法，其他方法被拒绝
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
@@ -96,9 +281,18 @@ func RegisterHandlers() {
 // 这是一个内部函数，简化了日志记录过程
 // 参数:
 // - message: 要记录的日志消息
-func write(message string) {
+// - at: 日志事件发生的时间
+// - host: 发送日志的主机名，为空时不写入
+func write(message string, at time.Time, host string) {
+	// 时间格式与标准库log.LstdFlags一致
+	stamp := at.Local().Format("2006/01/02 15:04:05")
+	if host != "" {
+		stamp += " " + host
+	}
 	// 使用全局日志记录器写入消息
 	// Printf格式化输出，%v是值的默认格式
 	// 添加换行符确保每条日志占一行
-	log.Printf("%v\n", message)
+	log.Printf("%v %v\n", stamp, message)
+	// 同时保存到内存中，供GET /log查询
+	recent.add(strings.TrimRight(message, "\n"))
 }
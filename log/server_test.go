@@ -0,0 +1,249 @@
+package log
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runAt 调用Run把日志写入path，测试结束时关闭缓冲并恢复全局记录器
+func runAt(t *testing.T, path string) error {
+	t.Helper()
+	savedLog, savedBuffer := log, buffer
+	t.Cleanup(func() {
+		if buffer != savedBuffer {
+			Close()
+		}
+		log, buffer = savedLog, savedBuffer
+	})
+	return Run(path, 0, 0)
+}
+
+func TestRunCreatesNestedDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a", "b", "app.log")
+	if err := runAt(t, path); err != nil {
+		t.Fatalf("Run(%q) = %v, want the directories created", path, err)
+	}
+
+	write("hello from a nested path", time.Now(), "")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "hello from a nested path") {
+		t.Errorf("log file contains %q, want the written message", data)
+	}
+}
+
+func TestRunRejectsUnwritablePath(t *testing.T) {
+	// 父路径是普通文件，无论以什么用户运行都无法在其下创建日志文件
+	parent := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(parent, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := runAt(t, filepath.Join(parent, "app.log")); err == nil {
+		t.Error("Run under a regular file succeeded, want an error")
+	}
+	if err := runAt(t, t.TempDir()); err == nil {
+		t.Error("Run on a directory succeeded, want an error")
+	}
+}
+
+func TestRunRejectsEmptyPath(t *testing.T) {
+	if err := runAt(t, ""); err == nil {
+		t.Error("Run with an empty path succeeded, want an error")
+	}
+}
+
+func TestLogHandlerTimesOutTricklingBody(t *testing.T) {
+	if err := runAt(t, filepath.Join(t.TempDir(), "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	saved := readTimeout
+	readTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { readTimeout = saved })
+
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	// 请求体每次只发送一个字节，且永远不会结束
+	pr, pw := io.Pipe()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer pw.Close()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+				if _, err := pw.Write([]byte("x")); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	start := time.Now()
+	res, err := http.Post(srv.URL+"/log", "text/plain", pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("trickling POST /log = %v, want %v", res.StatusCode, http.StatusRequestTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("handler held the request for %v, want it cut off near %v", elapsed, readTimeout)
+	}
+}
+
+// logMux 返回注册了日志服务接口的路由器，日志写入临时文件
+func logMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	if err := runAt(t, filepath.Join(t.TempDir(), "app.log")); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	return mux
+}
+
+func TestGetLogReturnsLastLines(t *testing.T) {
+	mux := logMux(t)
+	for _, msg := range []string{"tail one", "tail two", "tail three"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/log", strings.NewReader(msg)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("POST /log = %v, want %v", rec.Code, http.StatusOK)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log?n=2", nil))
+	if got := rec.Body.String(); got != "tail two\ntail three\n" {
+		t.Errorf("GET /log?n=2 = %q, want the last two lines", got)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log?n=2&format=json", nil))
+	var lines []string
+	if err := json.NewDecoder(rec.Body).Decode(&lines); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(lines) != "[tail two tail three]" {
+		t.Errorf("GET /log?n=2&format=json = %v, want the last two lines", lines)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/log?n=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /log?n=-1 = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRingKeepsOnlyNewestLines(t *testing.T) {
+	r := &ring{lines: make([]string, 3)}
+	for _, line := range []string{"a", "b", "c", "d", "e"} {
+		r.add(line)
+	}
+	if got := fmt.Sprint(r.last(10)); got != "[c d e]" {
+		t.Errorf("last(10) after wrapping = %v, want [c d e]", got)
+	}
+	if got := fmt.Sprint(r.last(2)); got != "[d e]" {
+		t.Errorf("last(2) = %v, want [d e]", got)
+	}
+}
+
+func TestWrittenLineUsesClientTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := runAt(t, path); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+
+	at := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	req := httptest.NewRequest(http.MethodPost, "/log", strings.NewReader("event under load"))
+	req.Header.Set(TimestampHeader, at.Format(time.RFC3339Nano))
+	req.Header.Set(HostHeader, "grading-7")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := at.Local().Format("2006/01/02 15:04:05") + " grading-7 event under load"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("log file contains %q, want the line %q stamped with the client time", data, want)
+	}
+}
+
+func TestClientSendsTimestampAndHost(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	cl := newClientLogger(srv.URL, 1)
+	before := time.Now()
+	cl.Write([]byte("stamped\n"))
+	cl.Close()
+
+	h := <-headers
+	at, err := time.Parse(time.RFC3339Nano, h.Get(TimestampHeader))
+	if err != nil {
+		t.Fatalf("%v header %q is not RFC3339Nano: %v", TimestampHeader, h.Get(TimestampHeader), err)
+	}
+	if at.Before(before) || at.After(time.Now()) {
+		t.Errorf("client timestamp %v is not the time of the Write", at)
+	}
+	if host, _ := os.Hostname(); h.Get(HostHeader) != host {
+		t.Errorf("%v header = %q, want %q", HostHeader, h.Get(HostHeader), host)
+	}
+}
+
+func TestShutdownFlushesBufferedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := runAt(t, path); err != nil {
+		t.Fatal(err)
+	}
+	const lines = 50
+	for i := 0; i < lines; i++ {
+		write(fmt.Sprintf("buffered line %d", i), time.Now(), "")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "buffered line "); got != lines {
+		t.Fatalf("file holds %d of %d lines after Shutdown", got, lines)
+	}
+	if !strings.Contains(string(data), fmt.Sprintf("buffered line %d\n", lines-1)) {
+		t.Error("last line is missing or truncated after Shutdown")
+	}
+}
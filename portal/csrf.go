@@ -0,0 +1,39 @@
+package portal
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+// csrfCookie 保存CSRF令牌的cookie名称，表单中的隐藏字段使用同样的名称
+const csrfCookie = "csrf_token"
+
+// csrfToken 返回请求已有的CSRF令牌，没有时生成一个并写入cookie
+// 采用双重提交: 表单提交时隐藏字段必须与cookie一致，其他站点无法读取cookie来伪造表单
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// validCSRF 检查表单中的令牌是否与cookie一致
+func validCSRF(r *http.Request) bool {
+	c, err := r.Cookie(csrfCookie)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.Value), []byte(r.FormValue(csrfCookie))) == 1
+}
@@ -2,9 +2,7 @@ package portal
 
 import (
 	"My_mimiDistributed/grades.go"
-	"My_mimiDistributed/registry"
-	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,12 +10,22 @@ import (
 	"strings"
 )
 
-func RegisterHandlers() {
-	http.Handle("/", http.RedirectHandler("/students", http.StatusPermanentRedirect))
+func RegisterHandlers(mux *http.ServeMux) {
+	mux.Handle("/", http.RedirectHandler("/students", http.StatusPermanentRedirect))
 
 	h := new(studentsHandler)
-	http.Handle("/students", h)
-	http.Handle("/students/", h)
+	mux.Handle("/students", h)
+	mux.Handle("/students/", h)
+	//单个学生的短链接，/student/{id} 与 /students/{id} 相同
+	mux.HandleFunc("/student/", func(w http.ResponseWriter, r *http.Request) {
+		r.URL.Path = "/students/" + strings.TrimPrefix(r.URL.Path, "/student/")
+		h.ServeHTTP(w, r)
+	})
+}
+
+// renderError 渲染友好的错误页面，用于成绩服务不可用等情况
+func renderError(w http.ResponseWriter, status int, message string) {
+	renderPage(w, status, "error.html", message)
 }
 
 type studentsHandler struct{}
@@ -36,13 +44,13 @@ func (sh studentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		sh.renderStudent(w, r, id)
-	case 4: // /students/{:id}/grades
+	case 4: // /students/{:id}/grades, /student/{:id}/grade
 		id, err := strconv.Atoi(pathSegments[2])
 		if err != nil {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
-		if strings.ToLower(pathSegments[3]) != "grades" {
+		if seg := strings.ToLower(pathSegments[3]); seg != "grades" && seg != "grade" {
 			w.WriteHeader(http.StatusNotFound)
 			return
 		}
@@ -54,102 +62,122 @@ func (sh studentsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 func (studentsHandler) renderStudents(w http.ResponseWriter, r *http.Request) {
-	var err error
-	defer func() {
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Println("Error retrieving students: ", err)
-		}
-	}()
+	page, size := pagination(r)
 
-	serviceURL, err := registry.GetProvider(registry.GradingService)
+	client, err := grades.NewClientFromRegistry()
 	if err != nil {
+		log.Println("Error retrieving students: ", err)
+		renderError(w, http.StatusServiceUnavailable, "The grading service is currently unavailable.")
 		return
 	}
 
-	res, err := http.Get(serviceURL + "/students")
+	// 成绩服务返回非200时不解码响应体，直接显示错误页面
+	body, err := client.OpenStudentsContext(r.Context())
 	if err != nil {
+		log.Println("Error retrieving students: ", err)
+		renderError(w, http.StatusBadGateway, "The grading service could not load the students.")
 		return
 	}
+	defer body.Close()
 
-	var s grades.Students
-	err = json.NewDecoder(res.Body).Decode(&s)
+	p, err := decodeStudentsPage(body, page, size)
 	if err != nil {
+		log.Println("Error retrieving students: ", err)
+		renderError(w, http.StatusBadGateway, "The grading service could not load the students.")
 		return
 	}
 
-	rootTemplate.Lookup("students.html").Execute(w, s)
+	// 模板直接写入ResponseWriter，不在内存中缓冲整页HTML
+	renderPage(w, http.StatusOK, "students.html", p)
 }
 
 func (studentsHandler) renderStudent(w http.ResponseWriter, r *http.Request, id int) {
-
-	var err error
-	defer func() {
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			log.Println("Error retrieving students: ", err)
-			return
-		}
-	}()
-
-	serviceURL, err := registry.GetProvider(registry.GradingService)
+	client, err := grades.NewClientFromRegistry()
 	if err != nil {
+		log.Println("Error retrieving student: ", err)
+		renderError(w, http.StatusServiceUnavailable, "The grading service is currently unavailable.")
 		return
 	}
 
-	res, err := http.Get(fmt.Sprintf("%v/students/%v", serviceURL, id))
-	if err != nil {
+	s, err := client.GetStudentContext(r.Context(), id)
+	if errors.Is(err, grades.ErrNotFound) {
+		renderError(w, http.StatusNotFound, fmt.Sprintf("Student %v was not found.", id))
 		return
 	}
-
-	var s grades.Student
-	err = json.NewDecoder(res.Body).Decode(&s)
 	if err != nil {
+		log.Println("Error retrieving student: ", err)
+		renderError(w, http.StatusBadGateway, "The grading service could not load this student.")
 		return
 	}
 
-	rootTemplate.Lookup("student.html").Execute(w, s)
+	renderPage(w, http.StatusOK, "student.html", s)
+}
+
+// gradeForm 是添加成绩表单的模板数据
+type gradeForm struct {
+	ID    int
+	Token string
+	Error string
+	Grade grades.Grade
+	// Score 保留用户输入的原始分数，校验失败重新渲染时回填
+	Score string
 }
 
+// GET 显示添加成绩的表单，POST 把成绩转发给成绩服务
+// 成功后重定向回学生页面，校验失败时带着错误信息重新渲染表单
 func (studentsHandler) renderGrades(w http.ResponseWriter, r *http.Request, id int) {
+	form := gradeForm{ID: id, Token: csrfToken(w, r)}
+	renderForm := func(status int) {
+		renderPage(w, status, "grade.html", form)
+	}
 
-	if r.Method != http.MethodPost {
+	switch r.Method {
+	case http.MethodGet:
+		renderForm(http.StatusOK)
+		return
+	case http.MethodPost:
+	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
-	defer func() {
-		w.Header().Add("location", fmt.Sprintf("/students/%v", id))
-		w.WriteHeader(http.StatusTemporaryRedirect)
-	}()
-	title := r.FormValue("Title")
-	gradeType := r.FormValue("Type")
-	score, err := strconv.ParseFloat(r.FormValue("Score"), 32)
-	if err != nil {
-		log.Println("Failed to parse score: ", err)
+
+	if !validCSRF(r) {
+		w.WriteHeader(http.StatusForbidden)
 		return
 	}
-	g := grades.Grade{
-		Title: title,
-		Type:  grades.GradeType(gradeType),
-		Score: float32(score),
+	form.Grade = grades.Grade{
+		Title: r.FormValue("Title"),
+		Type:  grades.GradeType(r.FormValue("Type")),
 	}
-	data, err := json.Marshal(g)
+	form.Score = r.FormValue("Score")
+	score, err := strconv.ParseFloat(form.Score, 32)
 	if err != nil {
-		log.Println("Failed to convert grade to JSON: ", g, err)
+		form.Error = "Score must be a number."
+		renderForm(http.StatusBadRequest)
+		return
 	}
+	form.Grade.Score = float32(score)
 
-	serviceURL, err := registry.GetProvider(registry.GradingService)
+	client, err := grades.NewClientFromRegistry()
 	if err != nil {
 		log.Println("Failed to retrieve instance of Grading Service", err)
+		renderError(w, http.StatusServiceUnavailable, "The grading service is currently unavailable.")
 		return
 	}
-	res, err := http.Post(fmt.Sprintf("%v/students/%v/grades", serviceURL, id), "application/json", bytes.NewBuffer(data))
-	if err != nil {
+
+	err = client.AddGradeContext(r.Context(), id, form.Grade)
+	var fe *grades.FieldError
+	switch {
+	case err == nil:
+		//POST之后使用303，浏览器以GET访问学生页面
+		http.Redirect(w, r, fmt.Sprintf("/students/%v", id), http.StatusSeeOther)
+	case errors.As(err, &fe):
+		form.Error = fe.Message
+		renderForm(http.StatusBadRequest)
+	case errors.Is(err, grades.ErrNotFound):
+		renderError(w, http.StatusNotFound, fmt.Sprintf("Student %v was not found.", id))
+	default:
 		log.Println("Failed to save grade to Grading Service", err)
-		return
-	}
-	if res.StatusCode != http.StatusCreated {
-		log.Println("Failed to save grade to Grading Service. Status: ", res.StatusCode)
-		return
+		renderError(w, http.StatusBadGateway, "The grade could not be saved.")
 	}
 }
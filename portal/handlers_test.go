@@ -0,0 +1,219 @@
+package portal
+
+import (
+	"My_mimiDistributed/grades.go"
+	"My_mimiDistributed/registry"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// withGradingService 把url登记为成绩服务的提供者，测试结束时移除
+func withGradingService(t *testing.T, url string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	if err := registry.RegisterUpdateHandler(mux, registry.Registration{ServiceUpdateURL: "http://test/services"}); err != nil {
+		t.Fatal(err)
+	}
+	send := func(field string) {
+		body := fmt.Sprintf(`{%q:[{"Name":%q,"URL":%q}]}`, field, registry.GradingService, url)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update handler returned %d for %s", rec.Code, body)
+		}
+	}
+	send("Added")
+	t.Cleanup(func() { send("Removed") })
+}
+
+// withPortalTemplates 在测试期间使用本目录下的模板文件
+func withPortalTemplates(t *testing.T) {
+	t.Helper()
+	saved, savedRoot := templateFiles, rootTemplate.Load()
+	templateFiles = []string{"students.html", "student.html", "error.html", "grade.html"}
+	t.Cleanup(func() {
+		templateFiles = saved
+		rootTemplate.Store(savedRoot)
+	})
+	if err := ImportTemplates(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// getPortal 通过门户的路由请求target
+func getPortal(target string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestStudentPageRendersGrades(t *testing.T) {
+	withPortalTemplates(t)
+	grading := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/students/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ID":7,"FirstName":"Ada","LastName":"Lovelace","Grades":[
+			{"Title":"Quiz 1","Type":"Quiz","Score":80},
+			{"Title":"Final","Type":"Exam","Score":90}]}`)
+	}))
+	defer grading.Close()
+	withGradingService(t, grading.URL)
+
+	rec := getPortal("/student/7")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"Lovelace, Ada", "Quiz 1", "Final", "80", "90", "Average: 85.0%"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestStudentPageNotFound(t *testing.T) {
+	withPortalTemplates(t)
+	grading := httptest.NewServer(http.NotFoundHandler())
+	defer grading.Close()
+	withGradingService(t, grading.URL)
+
+	rec := getPortal("/student/7")
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Student 7 was not found.") {
+		t.Errorf("response = %v %q, want 404 with the not found page", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStudentPageWithoutGradingService(t *testing.T) {
+	withPortalTemplates(t)
+
+	rec := getPortal("/student/7")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %v, want 503", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "The grading service is currently unavailable.") {
+		t.Errorf("page = %q, want the friendly error message", rec.Body.String())
+	}
+}
+
+// gradingStub 模拟成绩服务的添加成绩接口，按grades.Grade.Validate校验并记录收到的成绩
+func gradingStub(t *testing.T) *[]grades.Grade {
+	t.Helper()
+	var received []grades.Grade
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/students/7/grades" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var g grades.Grade
+		if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := g.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err)
+			return
+		}
+		received = append(received, g)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(g)
+	}))
+	t.Cleanup(srv.Close)
+	withGradingService(t, srv.URL)
+	return &received
+}
+
+// postGrade 带着有效的CSRF令牌向门户提交成绩表单
+func postGrade(form url.Values) *httptest.ResponseRecorder {
+	form.Set(csrfCookie, "token")
+	req := httptest.NewRequest(http.MethodPost, "/student/7/grade", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: csrfCookie, Value: "token"})
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGradeFormRendersWithToken(t *testing.T) {
+	withPortalTemplates(t)
+
+	rec := getPortal("/student/7/grade")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200", rec.Code)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookie {
+		t.Fatalf("cookies = %v, want a %v cookie", cookies, csrfCookie)
+	}
+	if !strings.Contains(rec.Body.String(), `value="`+cookies[0].Value+`"`) {
+		t.Errorf("form does not carry the token from the cookie:\n%s", rec.Body.String())
+	}
+}
+
+func TestGradeFormValidPostRedirects(t *testing.T) {
+	withPortalTemplates(t)
+	received := gradingStub(t)
+
+	rec := postGrade(url.Values{"Title": {"Quiz 1"}, "Type": {"Quiz"}, "Score": {"88"}})
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/students/7" {
+		t.Fatalf("response = %v to %q, want 303 to /students/7", rec.Code, rec.Header().Get("Location"))
+	}
+	want := grades.Grade{Title: "Quiz 1", Type: grades.GradeQuiz, Score: 88}
+	if len(*received) != 1 || (*received)[0] != want {
+		t.Errorf("grading service received %+v, want %+v", *received, want)
+	}
+}
+
+func TestGradeFormInvalidPostRerenders(t *testing.T) {
+	withPortalTemplates(t)
+	received := gradingStub(t)
+
+	rec := postGrade(url.Values{"Title": {"Quiz 1"}, "Type": {"Quiz"}, "Score": {"120"}})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %v, want 400", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "score 120 is not between 0 and 100") || !strings.Contains(body, `value="120"`) {
+		t.Errorf("form was not re-rendered with the error and the submitted score:\n%s", body)
+	}
+	if len(*received) != 0 {
+		t.Errorf("grading service stored %+v, want nothing", *received)
+	}
+
+	rec = postGrade(url.Values{"Title": {"Quiz 1"}, "Type": {"Quiz"}, "Score": {"abc"}})
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Score must be a number.") {
+		t.Errorf("response = %v %q, want 400 with the number message", rec.Code, rec.Body.String())
+	}
+}
+
+func TestGradeFormRejectsMissingToken(t *testing.T) {
+	withPortalTemplates(t)
+	received := gradingStub(t)
+
+	form := url.Values{"Title": {"Quiz 1"}, "Type": {"Quiz"}, "Score": {"88"}}
+	req := httptest.NewRequest(http.MethodPost, "/student/7/grade", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	mux := http.NewServeMux()
+	RegisterHandlers(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %v, want 403", rec.Code)
+	}
+	if len(*received) != 0 {
+		t.Errorf("grading service stored %+v without a CSRF token", *received)
+	}
+}
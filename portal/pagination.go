@@ -0,0 +1,78 @@
+package portal
+
+import (
+	"My_mimiDistributed/grades.go"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// studentsPage 是学生列表的一页，供students.html渲染
+type studentsPage struct {
+	Students grades.Students
+	Page     int
+	PageSize int
+	Total    int
+}
+
+func (p studentsPage) HasPrev() bool { return p.Page > 1 }
+func (p studentsPage) HasNext() bool { return p.Page*p.PageSize < p.Total }
+func (p studentsPage) PrevPage() int { return p.Page - 1 }
+func (p studentsPage) NextPage() int { return p.Page + 1 }
+
+// pagination 从查询参数page和size中读取分页设置，非法值回退为默认值
+func pagination(r *http.Request) (page, size int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	size, err = strconv.Atoi(r.URL.Query().Get("size"))
+	if err != nil || size < 1 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	return page, size
+}
+
+// decodeStudentsPage 逐个解码成绩服务返回的学生数组，只保留当前页的学生
+// 这样即使学生列表很大，内存中也只保存一页数据
+func decodeStudentsPage(body io.Reader, page, size int) (studentsPage, error) {
+	p := studentsPage{Page: page, PageSize: size}
+	start := (page - 1) * size
+
+	dec := json.NewDecoder(body)
+	tok, err := dec.Token()
+	if err != nil {
+		return p, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return p, fmt.Errorf("expected students array, got %v", tok)
+	}
+	for dec.More() {
+		if p.Total < start || p.Total >= start+size {
+			// 不在当前页的学生只需跳过，不必解码成结构体
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return p, err
+			}
+		} else {
+			var s grades.Student
+			if err := dec.Decode(&s); err != nil {
+				return p, err
+			}
+			p.Students = append(p.Students, s)
+		}
+		p.Total++
+	}
+	_, err = dec.Token()
+	return p, err
+}
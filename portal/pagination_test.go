@@ -0,0 +1,65 @@
+package portal
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"My_mimiDistributed/grades.go"
+)
+
+func TestDecodeStudentsPageKeepsOnlyRequestedPage(t *testing.T) {
+	ss := make(grades.Students, 10000)
+	for i := range ss {
+		ss[i] = grades.Student{ID: i + 1, FirstName: "Student"}
+	}
+	body, err := json.Marshal(ss)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := decodeStudentsPage(strings.NewReader(string(body)), 3, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Total != len(ss) {
+		t.Errorf("Total = %d, want %d", p.Total, len(ss))
+	}
+	if len(p.Students) != 50 {
+		t.Fatalf("decoded %d students, want one page of 50", len(p.Students))
+	}
+	if first, last := p.Students[0].ID, p.Students[49].ID; first != 101 || last != 150 {
+		t.Errorf("page 3 holds IDs %d..%d, want 101..150", first, last)
+	}
+	if !p.HasPrev() || !p.HasNext() {
+		t.Errorf("HasPrev=%v HasNext=%v on a middle page, want both true", p.HasPrev(), p.HasNext())
+	}
+}
+
+func TestDecodeStudentsPageLastPage(t *testing.T) {
+	p, err := decodeStudentsPage(strings.NewReader(`[{"ID":1},{"ID":2},{"ID":3}]`), 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Students) != 1 || p.Students[0].ID != 3 || p.HasNext() {
+		t.Errorf("last page = %+v, want only student 3 and no next page", p)
+	}
+}
+
+func TestDecodeStudentsPageRejectsNonArray(t *testing.T) {
+	if _, err := decodeStudentsPage(strings.NewReader(`{"ID":1}`), 1, 10); err == nil {
+		t.Error("decodeStudentsPage accepted an object, want an error")
+	}
+}
+
+func TestPaginationClampsQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/students?page=-2&size=100000", nil)
+	if page, size := pagination(r); page != 1 || size != maxPageSize {
+		t.Errorf("pagination() = %d, %d, want 1, %d", page, size, maxPageSize)
+	}
+	r = httptest.NewRequest("GET", "/students", nil)
+	if page, size := pagination(r); page != 1 || size != defaultPageSize {
+		t.Errorf("pagination() = %d, %d, want 1, %d", page, size, defaultPageSize)
+	}
+}
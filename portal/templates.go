@@ -1,21 +1,96 @@
 package portal
 
 import (
+	"errors"
 	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
 )
 
-var rootTemplate *template.Template
+// rootTemplate 保存当前使用的模板集合
+// 使用原子指针，重新导入模板时整体替换，不影响正在渲染的请求
+var rootTemplate atomic.Pointer[template.Template]
 
-func ImportTemplates() error {
-	var err error
+// DevMode 为true时每次渲染都重新解析模板文件，修改HTML后无需重启
+// 生产环境保持false，使用ImportTemplates缓存的模板
+var DevMode bool
 
-	rootTemplate, err = template.ParseFiles(
-		"../../portal/students.html",
-		"../../portal/student.html")
+// templateFiles 是门户使用的模板文件
+var templateFiles = []string{
+	"../../portal/students.html",
+	"../../portal/student.html",
+	"../../portal/error.html",
+	"../../portal/grade.html",
+}
 
+// ImportTemplates 解析模板文件并替换当前的模板集合
+// 可以重复调用，也可以在服务运行期间调用(例如开发时重新加载)
+func ImportTemplates() error {
+	t, err := template.ParseFiles(templateFiles...)
 	if err != nil {
 		return err
 	}
 
+	rootTemplate.Store(t)
 	return nil
 }
+
+// render 使用名为name的模板渲染data
+// DevMode下重新解析模板文件，否则使用缓存的模板
+func render(w io.Writer, name string, data any) error {
+	t := rootTemplate.Load()
+	if t == nil && !DevMode {
+		return errors.New("templates have not been imported")
+	}
+	if DevMode {
+		fresh, err := template.ParseFiles(templateFiles...)
+		if err != nil {
+			log.Println("Failed to reload templates: ", err)
+			return err
+		}
+		t = fresh
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
+
+// headerWriter 在第一次写入响应体时才写出状态码
+// 模板在输出任何内容之前失败时，仍然可以改为响应500
+type headerWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (hw *headerWriter) Write(data []byte) (int, error) {
+	if !hw.wrote {
+		hw.wrote = true
+		hw.ResponseWriter.WriteHeader(hw.status)
+	}
+	return hw.ResponseWriter.Write(data)
+}
+
+// renderPage 以status响应码渲染HTML页面，模板直接写入w，不在内存中缓冲整页
+// 渲染失败时记录日志；尚未写出任何内容时改为响应500，
+// 已经写出部分页面时状态码无法更改，只能记录日志
+// 参数:
+// - w: HTTP响应写入器
+// - status: 渲染成功时的响应码
+// - name: 模板名称
+// - data: 模板数据
+func renderPage(w http.ResponseWriter, status int, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	hw := &headerWriter{ResponseWriter: w, status: status}
+	err := render(hw, name, data)
+	if err == nil {
+		if !hw.wrote {
+			w.WriteHeader(status)
+		}
+		return
+	}
+	log.Printf("Failed to render %v: %v", name, err)
+	if !hw.wrote {
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,145 @@
+package portal
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// withTemplates 在测试期间使用由src解析出的模板集合，测试结束后恢复
+func withTemplates(t *testing.T, src string) {
+	t.Helper()
+	saved := rootTemplate.Load()
+	rootTemplate.Store(template.Must(template.New("root").Parse(src)))
+	t.Cleanup(func() { rootTemplate.Store(saved) })
+}
+
+func TestRenderPageWritesStatusOnSuccess(t *testing.T) {
+	withTemplates(t, `{{define "page.html"}}<p>{{.}}</p>{{end}}`)
+	rec := httptest.NewRecorder()
+	renderPage(rec, http.StatusBadRequest, "page.html", "hello")
+	if rec.Code != http.StatusBadRequest || rec.Body.String() != "<p>hello</p>" {
+		t.Fatalf("response = %v %q, want 400 <p>hello</p>", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRenderPageFailsWith500BeforeOutput(t *testing.T) {
+	withTemplates(t, `{{define "page.html"}}<p>{{.}}</p>{{end}}`)
+	rec := httptest.NewRecorder()
+	renderPage(rec, http.StatusOK, "missing.html", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %v, want 500 for a missing template", rec.Code)
+	}
+}
+
+func TestRenderPageWithoutTemplatesFails(t *testing.T) {
+	saved := rootTemplate.Load()
+	rootTemplate.Store(nil)
+	t.Cleanup(func() { rootTemplate.Store(saved) })
+
+	rec := httptest.NewRecorder()
+	renderPage(rec, http.StatusOK, "students.html", nil)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %v, want 500 when templates were never imported", rec.Code)
+	}
+}
+
+// writeTemplateFile 把src写入临时模板文件，并在测试期间只使用该文件
+func writeTemplateFile(t *testing.T, src string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved, savedRoot := templateFiles, rootTemplate.Load()
+	templateFiles = []string{path}
+	t.Cleanup(func() {
+		templateFiles = saved
+		rootTemplate.Store(savedRoot)
+	})
+}
+
+func TestImportTemplatesReplacesSet(t *testing.T) {
+	writeTemplateFile(t, `first {{.}}`)
+	if err := ImportTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(templateFiles[0], []byte(`second {{.}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := render(&out, "page.html", "render"); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "second render" {
+		t.Errorf("render after re-import = %q, want the latest template", out.String())
+	}
+}
+
+func TestImportTemplatesConcurrentWithRendering(t *testing.T) {
+	writeTemplateFile(t, `page {{.}}`)
+	if err := ImportTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var out strings.Builder
+				if err := render(&out, "page.html", j); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := ImportTemplates(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
+
+// renderAfterEdit 导入模板后把文件改为second，返回下一次渲染的结果
+func renderAfterEdit(t *testing.T, devMode bool) string {
+	t.Helper()
+	writeTemplateFile(t, `first {{.}}`)
+	saved := DevMode
+	DevMode = devMode
+	t.Cleanup(func() { DevMode = saved })
+	if err := ImportTemplates(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(templateFiles[0], []byte(`second {{.}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	renderPage(rec, http.StatusOK, "page.html", "render")
+	return rec.Body.String()
+}
+
+func TestDevModeReloadsEditedTemplate(t *testing.T) {
+	if got := renderAfterEdit(t, true); got != "second render" {
+		t.Errorf("render in dev mode = %q, want the edited template", got)
+	}
+}
+
+func TestProdModeKeepsCachedTemplate(t *testing.T) {
+	if got := renderAfterEdit(t, false); got != "first render" {
+		t.Errorf("render in prod mode = %q, want the cached template", got)
+	}
+}
@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ServiceStatus 描述一个已注册服务的依赖满足情况
+type ServiceStatus struct {
+	ServiceName ServiceName
+	ServiceURL  string
+
+	// Dependencies 记录每个依赖当前是否至少有一个可用的提供者
+	Dependencies map[ServiceName]bool
+
+	// Satisfied 表示所有依赖均已满足
+	Satisfied bool
+}
+
+// status 计算所有已注册服务的依赖满足情况
+// 返回:
+// - []ServiceStatus: 每个已注册服务的状态，顺序与注册顺序一致
+func (r *registry) status() []ServiceStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	available := make(map[ServiceName]bool)
+	for _, reg := range r.registrations {
+		available[reg.ServiceName] = true
+	}
+
+	result := make([]ServiceStatus, 0, len(r.registrations))
+	for _, reg := range r.registrations {
+		st := ServiceStatus{
+			ServiceName:  reg.ServiceName,
+			ServiceURL:   reg.ServiceURL,
+			Dependencies: make(map[ServiceName]bool, len(reg.RequireServices)),
+			Satisfied:    true,
+		}
+		for _, req := range reg.RequireServices {
+			st.Dependencies[req] = available[req]
+			if !available[req] {
+				st.Satisfied = false
+			}
+		}
+		result = append(result, st)
+	}
+	return result
+}
+
+// AdminStatusHandler 实现http.Handler接口
+// 通过GET /admin/status展示每个服务的依赖是否满足，便于快速发现断裂的依赖链
+type AdminStatusHandler struct{}
+
+// ServeHTTP 以JSON数组返回所有已注册服务的依赖满足情况
+// 参数:
+// - w: HTTP响应写入器
+// - r: HTTP请求对象
+func (h AdminStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 依赖状态暴露了所有服务的地址，与/services使用同一个共享密钥保护
+	if !authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="registry"`)
+		http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := json.Marshal(reg.status())
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
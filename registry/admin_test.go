@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminStatusRequiresAPIKey(t *testing.T) {
+	SetAPIKey("secret")
+	t.Cleanup(func() { SetAPIKey("") })
+
+	rec := httptest.NewRecorder()
+	AdminStatusHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status without key = %v, want %v", rec.Code, http.StatusUnauthorized)
+	}
+	if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Fatal("401 response is missing WWW-Authenticate")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/status", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	AdminStatusHandler{}.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status with key = %v, want %v", rec.Code, http.StatusOK)
+	}
+}
+
+// adminStatus 通过AdminStatusHandler获取并解码所有服务的依赖状态
+func adminStatus(t *testing.T) []ServiceStatus {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	AdminStatusHandler{}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /admin/status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var statuses []ServiceStatus
+	if err := json.NewDecoder(rec.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	return statuses
+}
+
+func TestAdminStatusReportsDependencySatisfaction(t *testing.T) {
+	dependent := newPatchRecorder(t)
+	withRegistrations(t, []Registration{{
+		ServiceName:      GradingService,
+		ServiceURL:       "http://grading",
+		ServiceUpdateURL: dependent.URL,
+		RequireServices:  []ServiceName{LogService},
+	}})
+
+	statuses := adminStatus(t)
+	if len(statuses) != 1 || statuses[0].Satisfied || statuses[0].Dependencies[LogService] {
+		t.Fatalf("status with LogService missing = %+v, want grading unsatisfied", statuses)
+	}
+
+	logSink := newPatchRecorder(t)
+	if err := reg.add(Registration{ServiceName: LogService, ServiceURL: "http://log", ServiceUpdateURL: logSink.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses = adminStatus(t)
+	if len(statuses) != 2 {
+		t.Fatalf("status lists %d services, want 2", len(statuses))
+	}
+	if st := statuses[0]; !st.Satisfied || !st.Dependencies[LogService] {
+		t.Errorf("grading status after LogService registered = %+v, want satisfied", st)
+	}
+	if st := statuses[1]; !st.Satisfied || len(st.Dependencies) != 0 {
+		t.Errorf("log status = %+v, want satisfied with no dependencies", st)
+	}
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"context"
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// APIKeyEnv 是配置注册中心共享密钥的环境变量
+// 注册中心和各服务读取同一个变量，未设置时不启用认证
+const APIKeyEnv = "REGISTRY_API_KEY"
+
+// apiKey 是访问注册中心所需的共享密钥，为空表示不启用认证
+// 注册中心用它校验请求，客户端用它为注册、注销和预检请求附加Authorization请求头
+var (
+	apiKey   = os.Getenv(APIKeyEnv)
+	apiKeyMu sync.RWMutex
+)
+
+// SetAPIKey 设置访问注册中心的共享密钥
+// 参数:
+// - key: 共享密钥，为空时关闭认证，已有的部署无需任何配置即可继续工作
+func SetAPIKey(key string) {
+	apiKeyMu.Lock()
+	defer apiKeyMu.Unlock()
+	apiKey = key
+}
+
+// currentAPIKey 返回当前配置的共享密钥
+func currentAPIKey() string {
+	apiKeyMu.RLock()
+	defer apiKeyMu.RUnlock()
+	return apiKey
+}
+
+// authorized 检查请求是否携带了正确的共享密钥
+// 请求头格式为"Authorization: Bearer <key>"，未配置密钥时所有请求都被放行
+// 使用常量时间比较，避免通过响应时间推测密钥
+func authorized(r *http.Request) bool {
+	key := currentAPIKey()
+	if key == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1
+}
+
+// newRegistryRequest 创建发往注册中心的请求，配置了共享密钥时附加Authorization请求头
+// 发往其他服务的请求(依赖推送、心跳检查)不携带密钥，避免密钥泄露给普通服务
+// 参数与newRequest相同
+func newRegistryRequest(ctx context.Context, method, url, contentType string,
+	body io.Reader) (*http.Request, error) {
+	req, err := newRequest(ctx, method, url, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	if key := currentAPIKey(); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	return req, nil
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+)
+
+// DefaultHashReplicas 是一致性哈希环上每个服务实例的默认虚拟节点数
+// 虚拟节点越多，键在实例间的分布越均匀
+const DefaultHashReplicas = 100
+
+// ConsistentHashBalancer 是基于一致性哈希的负载均衡器
+// 对于缓存或有状态的后端，同一个路由键(例如学生ID)总是映射到同一个服务实例；
+// 实例增减时，只有落在变化实例附近的一小部分键会被重新映射
+type ConsistentHashBalancer struct {
+	// replicas 是每个实例在哈希环上的虚拟节点数
+	replicas int
+
+	// ring 是排序后的虚拟节点哈希值
+	ring []uint32
+
+	// nodes 是虚拟节点哈希值到服务URL的映射
+	nodes map[uint32]string
+}
+
+// NewConsistentHashBalancer 根据服务URL列表构建一致性哈希环
+// 参数:
+// - replicas: 每个实例的虚拟节点数，小于等于0时使用DefaultHashReplicas
+// - urls: 服务实例URL列表
+// 返回:
+// - *ConsistentHashBalancer: 构建好的负载均衡器
+func NewConsistentHashBalancer(replicas int, urls []string) *ConsistentHashBalancer {
+	if replicas <= 0 {
+		replicas = DefaultHashReplicas
+	}
+	b := &ConsistentHashBalancer{
+		replicas: replicas,
+		nodes:    make(map[uint32]string, replicas*len(urls)),
+	}
+	for _, u := range urls {
+		for i := 0; i < replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(strconv.Itoa(i) + u))
+			if _, ok := b.nodes[h]; ok {
+				continue
+			}
+			b.nodes[h] = u
+			b.ring = append(b.ring, h)
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool { return b.ring[i] < b.ring[j] })
+	return b
+}
+
+// Pick 返回路由键对应的服务实例
+// 在哈希环上顺时针查找第一个不小于键哈希值的虚拟节点
+// 参数:
+// - key: 路由键
+// 返回:
+// - string: 服务URL
+// - bool: 环为空时返回false
+func (b *ConsistentHashBalancer) Pick(key string) (string, bool) {
+	if len(b.ring) == 0 {
+		return "", false
+	}
+	h := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(b.ring), func(i int) bool { return b.ring[i] >= h })
+	if idx == len(b.ring) {
+		idx = 0
+	}
+	return b.nodes[b.ring[idx]], true
+}
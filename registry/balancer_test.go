@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConsistentHashSameKeySameInstance(t *testing.T) {
+	urls := []string{"http://grading-1", "http://grading-2", "http://grading-3"}
+	b := NewConsistentHashBalancer(0, urls)
+	// 用相同URL重新构建的环必须给出相同的映射
+	rebuilt := NewConsistentHashBalancer(0, []string{urls[2], urls[0], urls[1]})
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprint(i)
+		first, ok := b.Pick(key)
+		if !ok {
+			t.Fatal("Pick on a non-empty ring returned false")
+		}
+		if again, _ := b.Pick(key); again != first {
+			t.Fatalf("key %v mapped to %v then %v", key, first, again)
+		}
+		if other, _ := rebuilt.Pick(key); other != first {
+			t.Fatalf("key %v mapped to %v, but %v on a ring built in another order", key, first, other)
+		}
+	}
+}
+
+func TestConsistentHashAddingInstanceRemapsBoundedFraction(t *testing.T) {
+	const keys = 10000
+	before := NewConsistentHashBalancer(0, []string{"http://grading-1", "http://grading-2", "http://grading-3"})
+	after := NewConsistentHashBalancer(0, []string{"http://grading-1", "http://grading-2", "http://grading-3", "http://grading-4"})
+
+	moved := 0
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprint(i)
+		old, _ := before.Pick(key)
+		cur, _ := after.Pick(key)
+		if old != cur {
+			// 只允许键迁移到新实例，已有实例之间不应互相交换键
+			if cur != "http://grading-4" {
+				t.Fatalf("key %v moved from %v to %v, want moves only onto the new instance", key, old, cur)
+			}
+			moved++
+		}
+	}
+	// 理想情况下约1/4的键迁移到新实例，留出余量应对哈希分布不均
+	if moved == 0 || moved > keys/2 {
+		t.Errorf("adding a fourth instance remapped %d of %d keys, want roughly a quarter", moved, keys)
+	}
+}
+
+func TestConsistentHashEmptyRing(t *testing.T) {
+	if url, ok := NewConsistentHashBalancer(0, nil).Pick("42"); ok {
+		t.Errorf("Pick on an empty ring = %q, true; want false", url)
+	}
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// BatchItem 是批量操作中单个元素的处理结果
+type BatchItem struct {
+	// Index 是元素在请求数组中的下标
+	Index int
+
+	// Status 是该元素的HTTP状态码，与单独处理该元素时的响应码一致
+	Status int
+
+	// Error 是失败原因，成功时为空
+	Error string `json:",omitempty"`
+}
+
+// BatchResult 是批量操作(批量注册、批量添加成绩、导入等)统一的结果格式
+// 每个元素单独成功或失败，调用方据此准确定位失败的元素及原因
+type BatchResult struct {
+	// Items 按请求中的顺序记录每个元素的结果
+	Items []BatchItem
+
+	// Failed 是失败元素的数量
+	Failed int
+}
+
+// Record 记录下标为index的元素的结果
+// 参数:
+// - index: 元素在请求数组中的下标
+// - status: 该元素的HTTP状态码
+// - err: 失败原因，成功时为nil
+func (b *BatchResult) Record(index, status int, err error) {
+	item := BatchItem{Index: index, Status: status}
+	if err != nil {
+		item.Error = err.Error()
+		b.Failed++
+	}
+	b.Items = append(b.Items, item)
+}
+
+// Status 返回整个批量请求的响应码
+// 全部成功时为200，存在失败的元素时为207(Multi-Status)，逐项结果见Items
+func (b BatchResult) Status() int {
+	if b.Failed > 0 {
+		return http.StatusMultiStatus
+	}
+	return http.StatusOK
+}
+
+// Err 在存在失败的元素时返回汇总了失败下标的错误，全部成功时返回nil
+func (b BatchResult) Err() error {
+	if b.Failed == 0 {
+		return nil
+	}
+	var failed []int
+	for _, item := range b.Items {
+		if item.Error != "" {
+			failed = append(failed, item.Index)
+		}
+	}
+	return fmt.Errorf("%d of %d batch items failed: indices %v", b.Failed, len(b.Items), failed)
+}
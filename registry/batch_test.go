@@ -0,0 +1,37 @@
+package registry
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBatchResultPinpointsFailures(t *testing.T) {
+	var result BatchResult
+	result.Record(0, http.StatusCreated, nil)
+	result.Record(1, http.StatusBadRequest, errors.New("bad item"))
+	result.Record(2, http.StatusCreated, nil)
+	result.Record(3, http.StatusNotFound, errors.New("missing"))
+
+	if result.Status() != http.StatusMultiStatus {
+		t.Fatalf("Status() = %v, want %v", result.Status(), http.StatusMultiStatus)
+	}
+	if result.Failed != 2 {
+		t.Fatalf("Failed = %v, want 2", result.Failed)
+	}
+	if err := result.Err(); err == nil || !strings.Contains(err.Error(), "[1 3]") {
+		t.Fatalf("Err() = %v, want it to name indices 1 and 3", err)
+	}
+	if result.Items[1].Error != "bad item" || result.Items[3].Status != http.StatusNotFound {
+		t.Fatalf("items = %+v", result.Items)
+	}
+}
+
+func TestBatchResultAllSucceeded(t *testing.T) {
+	var result BatchResult
+	result.Record(0, http.StatusCreated, nil)
+	if result.Status() != http.StatusOK || result.Err() != nil {
+		t.Fatalf("Status() = %v, Err() = %v; want 200 and nil", result.Status(), result.Err())
+	}
+}
@@ -2,36 +2,63 @@ package registry
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
+	"math"
 	"math/rand/v2"
+	"mime"
 	"net/http"
-	"net/url"
+	neturl "net/url"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// RegisterService 向注册中心注册微服务
-// 业务流程:
-// 1. 解析更新URL并设置更新处理器
-// 2. 将注册信息序列化为JSON
-// 3. 发送POST请求到注册中心
-// 4. 验证注册成功
+// RegisterUpdateHandler 在mux上注册接收依赖更新通知的处理器
+// 处理器的路径取自r.ServiceUpdateURL，须在RegisterService之前调用，
+// 保证注册中心推送依赖信息时服务已经能够处理
 // 参数:
-// - r: 包含服务名称、URL和依赖信息的注册对象
+// - mux: 服务自己的路由器
+// - r: 服务注册信息
 // 返回:
-// - error: 注册过程中的错误
-func RegisterService(r Registration) error {
+// - error: ServiceUpdateURL无法解析时返回错误
+func RegisterUpdateHandler(mux *http.ServeMux, r Registration) error {
 	// 解析ServiceUpdateURL，提取路径部分
-	// 此URL将用于接收依赖服务更新通知
-	serviceUpdateURL, err := url.Parse(r.ServiceUpdateURL)
+	serviceUpdateURL, err := neturl.Parse(r.ServiceUpdateURL)
 	if err != nil {
 		return err
 	}
-
-	// 注册HTTP处理器来接收依赖更新通知
 	// 所有发送到ServiceUpdateURL的请求都会由serviceUpdateHandler处理
-	http.Handle(serviceUpdateURL.Path, &serviceUpdateHandler{})
+	mux.Handle(serviceUpdateURL.Path, &serviceUpdateHandler{})
+	return nil
+}
 
+// RegisterService 向注册中心注册微服务，等价于使用context.Background()调用RegisterServiceContext
+// 参数:
+// - r: 包含服务名称、URL和依赖信息的注册对象
+// 返回:
+// - error: 注册过程中的错误
+func RegisterService(r Registration) error {
+	return RegisterServiceContext(context.Background(), r)
+}
+
+// RegisterServiceContext 向注册中心注册微服务
+// 调用前需先通过RegisterUpdateHandler注册接收依赖更新的处理器
+// 业务流程:
+// 1. 将注册信息序列化为JSON
+// 2. 发送POST请求到注册中心
+// 3. 验证注册成功
+// 参数:
+// - ctx: 请求上下文，取消时注册请求随之中止
+// - r: 包含服务名称、URL和依赖信息的注册对象
+// 返回:
+// - error: 注册过程中的错误
+func RegisterServiceContext(ctx context.Context, r Registration) error {
 	// 创建一个字节缓冲区，用于存储JSON编码后的注册信息
 	buf := new(bytes.Buffer)
 
@@ -39,17 +66,22 @@ func RegisterService(r Registration) error {
 	enc := json.NewEncoder(buf)
 
 	// 将注册信息编码为JSON
-	err = enc.Encode(r)
+	err := enc.Encode(r)
 	if err != nil {
 		return err
 	}
 
 	// 发送HTTP POST请求到注册中心的/services端点
 	// 携带JSON格式的注册信息作为请求体
-	res, err := http.Post(ServicesURL, "application/json", buf)
+	req, err := newRegistryRequest(ctx, http.MethodPost, servicesURL(), "application/json", buf)
 	if err != nil {
 		return err
 	}
+	res, err := do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
 
 	// 检查响应状态码，确保注册成功
 	if res.StatusCode != http.StatusOK {
@@ -59,6 +91,37 @@ func RegisterService(r Registration) error {
 	return nil
 }
 
+// NegativeCacheTTL 是查找未命中结果的缓存时间
+// 时间足够短，新注册的服务能很快被发现
+const NegativeCacheTTL = 500 * time.Millisecond
+
+// PingTimeout 是检查注册中心是否可达的超时时间
+const PingTimeout = 3 * time.Second
+
+// Ping 检查注册中心是否可达
+// 只要注册中心返回了HTTP响应(无论状态码)即视为可达
+// 返回:
+// - error: 注册中心不可达时返回错误
+func Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), PingTimeout)
+	defer cancel()
+
+	req, err := newRegistryRequest(ctx, http.MethodHead, servicesURL(), "", nil)
+	if err != nil {
+		return err
+	}
+	res, err := do(req)
+	if err != nil {
+		return fmt.Errorf("registry unreachable at %v: %w", servicesURL(), err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+// DeregisterTimeout 是注销请求的超时时间
+// 超时后注销失败，但服务可以继续完成自身的关闭流程
+const DeregisterTimeout = 5 * time.Second
+
 // ShutdownService 向注册中心发送服务注销请求
 // 服务关闭时调用此函数，从注册中心移除服务信息
 // 参数:
@@ -66,18 +129,55 @@ func RegisterService(r Registration) error {
 // 返回:
 // - error: 注销过程中的错误
 func ShutdownService(url string) error {
-	// 创建DELETE请求，携带服务URL作为请求体
-	req, err := http.NewRequest(http.MethodDelete, ServicesURL,
-		bytes.NewBuffer([]byte(url)))
+	return DeregisterService(url, ReasonShutdown)
+}
+
+// DeregisterService 向注册中心发送带有移除原因的注销请求
+// 例如运维人员下线服务时使用ReasonDrained
+// 参数:
+// - url: 要注销的服务URL
+// - reason: 移除原因，会随Removed通知推送给依赖方
+// 返回:
+// - error: 注销过程中的错误
+func DeregisterService(url string, reason RemovalReason) error {
+	// 注销请求的时间有上限，注册中心无响应时也不会阻塞服务关闭
+	ctx, cancel := context.WithTimeout(context.Background(), DeregisterTimeout)
+	defer cancel()
+	return DeregisterServiceContext(ctx, url, reason)
+}
+
+// DeregisterServiceContext 向注册中心发送带有移除原因的注销请求
+// 参数:
+// - ctx: 请求上下文，取消或到期时注销请求随之中止
+// - url: 要注销的服务URL
+// - reason: 移除原因，会随Removed通知推送给依赖方
+// 返回:
+// - error: 注销过程中的错误，服务已不在注册表中时返回nil
+func DeregisterServiceContext(ctx context.Context, url string, reason RemovalReason) error {
+
+	// 创建DELETE请求，携带{"url":...}形式的JSON请求体，移除原因作为查询参数
+	data, err := json.Marshal(deregistration{URL: url})
+	if err != nil {
+		return err
+	}
+	req, err := newRegistryRequest(ctx, http.MethodDelete,
+		servicesURL()+"?reason="+neturl.QueryEscape(string(reason)), "application/json",
+		bytes.NewReader(data))
 	if err != nil {
 		return err
 	}
 
 	// 发送请求到注册中心
-	res, err := http.DefaultClient.Do(req)
+	res, err := do(req)
 	if err != nil {
 		return err
 	}
+	res.Body.Close()
+
+	// 404表示服务已不在注册表中(例如已被心跳检查剔除)，注销的目的已经达到
+	if res.StatusCode == http.StatusNotFound {
+		return nil
+	}
 
 	// 检查响应状态码，确保注销成功
 	if res.StatusCode != http.StatusOK {
@@ -95,6 +195,48 @@ type providers struct {
 	// 例如: {"LogService": ["http://localhost:4000", "http://localhost:4001"]}
 	services map[ServiceName][]string
 
+	// endpoints是服务URL到其命名端点的映射
+	// 只记录声明了Endpoints的服务实例
+	endpoints map[string]map[string]string
+
+	// metadata是服务URL到其标签的映射
+	// 只记录声明了Metadata的服务实例
+	metadata map[string]map[string]string
+
+	// weights是服务URL到其负载均衡权重的映射
+	// 只记录显式设置了Weight的服务实例，其余实例权重为DefaultWeight
+	weights map[string]int
+
+	// rings是服务类型到一致性哈希环的映射，用于按键粘性路由
+	// 在Update中随服务列表变化重建
+	rings map[ServiceName]*ConsistentHashBalancer
+
+	// clock 是客户端获取时间的来源，测试中可替换为FakeClock
+	clock Clock
+
+	// counters是服务类型到轮询计数器的映射，在首次发现该服务时创建
+	counters map[ServiceName]*atomic.Uint64
+
+	// self是调用方自身的服务URL，设置后get不会返回该URL
+	self string
+
+	// misses是近期查找未命中的服务类型到缓存过期时间的映射
+	// 发现该服务的新实例时立即清除
+	misses map[ServiceName]time.Time
+
+	// onAdded和onRemoved是服务类型到回调列表的映射
+	// 依赖服务的实例出现或消失时依次调用
+	onAdded   map[ServiceName][]func(url string)
+	onRemoved map[ServiceName][]func(url string)
+
+	// failures是服务URL到近期失败记录的映射，由ReportFailure更新
+	// get优先选择近期失败次数最少的实例
+	failures map[string]failureRecord
+
+	// onUpdate是服务类型到批量回调列表的映射
+	// 每次应用patch后，对实例发生变化的服务类型调用一次
+	onUpdate map[ServiceName][]func(added, removed []string)
+
 	// mutex保护并发访问
 	mutex *sync.RWMutex
 }
@@ -103,8 +245,52 @@ type providers struct {
 // 当接收到注册中心发送的patch对象时调用此方法
 // 它会更新本地缓存的服务提供者列表
 // 参数:
-// - pat: 包含新增和移除服务的patch对象
+// - pat: 包含新增、移除和更新服务的patch对象
 func (p *providers) Update(pat patch) {
+	added, removed := p.apply(pat)
+
+	// 在锁外调用回调，回调中可以安全地调用GetProvider等函数
+	p.mutex.RLock()
+	onAdded, onRemoved, onUpdate := p.onAdded, p.onRemoved, p.onUpdate
+	p.mutex.RUnlock()
+	for _, e := range removed {
+		for _, cb := range onRemoved[e.Name] {
+			cb(e.URL)
+		}
+	}
+	for _, e := range added {
+		for _, cb := range onAdded[e.Name] {
+			cb(e.URL)
+		}
+	}
+
+	// 按服务类型汇总本次变化，每个类型只调用一次批量回调
+	if len(onUpdate) == 0 {
+		return
+	}
+	addedURLs := make(map[ServiceName][]string)
+	removedURLs := make(map[ServiceName][]string)
+	for _, e := range added {
+		addedURLs[e.Name] = append(addedURLs[e.Name], e.URL)
+	}
+	for _, e := range removed {
+		removedURLs[e.Name] = append(removedURLs[e.Name], e.URL)
+	}
+	for name, cbs := range onUpdate {
+		if len(addedURLs[name]) == 0 && len(removedURLs[name]) == 0 {
+			continue
+		}
+		for _, cb := range cbs {
+			cb(addedURLs[name], removedURLs[name])
+		}
+	}
+}
+
+// apply 将patch应用到本地缓存
+// 返回:
+// - added: 实际新增的服务实例
+// - removed: 实际移除的服务实例
+func (p *providers) apply(pat patch) (added, removed []patchEntry) {
 	// 加锁确保并发安全
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
@@ -115,74 +301,641 @@ func (p *providers) Update(pat patch) {
 		if _, ok := p.services[patchEntry.Name]; !ok {
 			p.services[patchEntry.Name] = make([]string, 0)
 		}
+		// 重新注册时注册中心会再次推送已知的实例，已存在的URL只更新附加信息
+		known := slices.Contains(p.services[patchEntry.Name], patchEntry.URL)
 		// 将服务URL添加到对应服务类型的列表中
-		p.services[patchEntry.Name] = append(p.services[patchEntry.Name],
-			patchEntry.URL)
+		if !known {
+			p.services[patchEntry.Name] = append(p.services[patchEntry.Name],
+				patchEntry.URL)
+		}
+		// 记录服务的命名端点、标签和权重，已知实例以本次注册的信息为准
+		p.setAttributes(patchEntry.URL, patchEntry)
+		if !known {
+			added = append(added, patchEntry)
+		}
+		// 服务已有实例，清除未命中缓存
+		delete(p.misses, patchEntry.Name)
+		if _, ok := p.counters[patchEntry.Name]; !ok {
+			p.counters[patchEntry.Name] = new(atomic.Uint64)
+		}
 	}
 
 	// 处理移除的服务
+	// 构建不含该URL的新切片而不是原地拼接，已经交给其他goroutine的旧切片保持不变，
+	// 同一URL出现多次时也会全部移除
 	for _, patchEntry := range pat.Removed {
-		if providersURLs, ok := p.services[patchEntry.Name]; ok {
-			// 遍历URL列表，找到并移除匹配的URL
-			for i := range providersURLs {
-				if providersURLs[i] == patchEntry.URL {
-					p.services[patchEntry.Name] = append(providersURLs[:i],
-						providersURLs[i+1:]...)
-					break
-				}
+		providersURLs := p.services[patchEntry.Name]
+		if !slices.Contains(providersURLs, patchEntry.URL) {
+			continue
+		}
+		p.services[patchEntry.Name] = exclude(providersURLs, patchEntry.URL)
+		delete(p.endpoints, patchEntry.URL)
+		delete(p.metadata, patchEntry.URL)
+		delete(p.weights, patchEntry.URL)
+		delete(p.failures, patchEntry.URL)
+		removed = append(removed, patchEntry)
+	}
+
+	// 处理URL发生变化的服务，在副本的原位置替换URL
+	// URL没有变化时只更新附加信息，实例没有增减，不触发回调
+	for _, entry := range pat.Updated {
+		if entry.PrevURL == entry.URL {
+			if slices.Contains(p.services[entry.Name], entry.URL) {
+				p.setAttributes(entry.URL, entry)
+			}
+			continue
+		}
+		for i, u := range p.services[entry.Name] {
+			if u == entry.PrevURL {
+				urls := slices.Clone(p.services[entry.Name])
+				urls[i] = entry.URL
+				p.services[entry.Name] = urls
+				delete(p.endpoints, entry.PrevURL)
+				delete(p.metadata, entry.PrevURL)
+				delete(p.weights, entry.PrevURL)
+				delete(p.failures, entry.PrevURL)
+				p.setAttributes(entry.URL, entry)
+				// 对回调而言，URL变化相当于旧URL移除、新URL加入
+				removed = append(removed, patchEntry{Name: entry.Name, URL: entry.PrevURL})
+				added = append(added, entry)
+				break
 			}
 		}
 	}
+
+	// 重建受影响服务的一致性哈希环
+	// 已知实例重新注册时以Added推送，权重变化同样会触发重建
+	for _, entries := range [][]patchEntry{pat.Added, pat.Removed, pat.Updated} {
+		for _, patchEntry := range entries {
+			p.rebuildRing(patchEntry.Name)
+		}
+	}
+	return added, removed
 }
 
+// setAttributes 用entry中的命名端点、标签和权重替换url已记录的信息，调用方需持有写锁
+// entry中为空的字段会清除旧值，实例取消标签或权重后不会保留过期的信息
+func (p *providers) setAttributes(url string, entry patchEntry) {
+	delete(p.endpoints, url)
+	if len(entry.Endpoints) > 0 {
+		p.endpoints[url] = entry.Endpoints
+	}
+	delete(p.metadata, url)
+	if len(entry.Metadata) > 0 {
+		p.metadata[url] = entry.Metadata
+	}
+	delete(p.weights, url)
+	if entry.Weight != nil {
+		p.weights[url] = weightOf(entry.Weight)
+	}
+}
+
+// Strategy 是从多个服务实例中选择一个的负载均衡策略
+type Strategy int
+
+const (
+	// RoundRobin 按顺序轮流选择每个实例，请求在实例间均匀分布
+	RoundRobin Strategy = iota
+
+	// Random 随机选择一个实例
+	Random
+)
+
 // get 根据服务名称获取一个可用的服务URL
-// 如果有多个实例，会随机选择一个，实现简单的负载均衡
+// 如果有多个实例，按照strategy选择一个，实现简单的负载均衡
+// 两种策略都按实例权重分配请求，权重为0的实例不会被选中
+// 只在近期失败次数最少的实例中选择，所有实例都在失败时仍然返回其中之一；
+// 其中又只选择优先级数值最小的实例(见PriorityKey)，实现主备路由
 // 参数:
 // - name: 服务名称
+// - strategy: 负载均衡策略
 // 返回:
 // - string: 服务URL
 // - error: 查找过程中的错误
-func (p providers) get(name ServiceName) (string, error) {
+func (p *providers) get(name ServiceName, strategy Strategy) (string, error) {
 	// 获取指定服务类型的所有URL
 	p.mutex.RLock()
-	defer p.mutex.RUnlock()
 
-	providers, ok := p.services[name]
-	if !ok {
+	// 近期已确认不存在的服务直接返回，避免调用方在循环中反复查找
+	if until, ok := p.misses[name]; ok && p.clock.Now().Before(until) {
+		p.mutex.RUnlock()
 		return "", fmt.Errorf("no providers available for service %v", name)
 	}
 
-	// 随机选择一个URL，实现简单的负载均衡
-	idx := int(rand.Float32() * float32(len(providers)))
-	return providers[idx], nil
+	providers := p.services[name]
+	// 排除调用方自身，避免服务选中自己造成调用环路
+	if p.self != "" {
+		providers = exclude(providers, p.self)
+	}
+	// 避开近期频繁失败的实例，再在剩余实例中选择优先级最高(数值最小)的一组
+	providers = p.lowestPriority(p.leastFailing(providers))
+	// 计算权重总和，权重为0(下线引流)的实例不参与选择
+	total := 0
+	for _, u := range providers {
+		total += p.weight(u)
+	}
+	if total == 0 {
+		p.mutex.RUnlock()
+		// 记录未命中，在NegativeCacheTTL内的后续查找直接返回
+		p.mutex.Lock()
+		p.misses[name] = p.clock.Now().Add(NegativeCacheTTL)
+		p.mutex.Unlock()
+		return "", fmt.Errorf("no providers available for service %v", name)
+	}
+	defer p.mutex.RUnlock()
+
+	// 在[0, total)中取一个位置，落在哪个实例的权重区间内就选择哪个实例
+	var n int
+	switch strategy {
+	case Random:
+		n = rand.IntN(total)
+	default:
+		// 每个服务类型一个原子计数器，递增后对权重总和取模
+		if counter, ok := p.counters[name]; ok {
+			n = int((counter.Add(1) - 1) % uint64(total))
+		}
+	}
+	for _, u := range providers {
+		if n < p.weight(u) {
+			return u, nil
+		}
+		n -= p.weight(u)
+	}
+	return providers[len(providers)-1], nil
+}
+
+// FailureHalfLife 是实例失败次数衰减一半所需的时间
+// 失败的实例在一段时间内少被选中，之后逐渐恢复正常的负载分配
+const FailureHalfLife = 30 * time.Second
+
+// failureRecord 是一个服务实例的近期失败记录
+type failureRecord struct {
+	// count 是at时刻的失败次数，随时间按FailureHalfLife指数衰减
+	count float64
+	at    time.Time
+}
+
+// decayed 返回now时刻衰减后的失败次数
+func (f failureRecord) decayed(now time.Time) float64 {
+	elapsed := now.Sub(f.at)
+	if elapsed <= 0 {
+		return f.count
+	}
+	return f.count * math.Exp2(-float64(elapsed)/float64(FailureHalfLife))
+}
+
+// ReportFailure 报告一次对服务实例的调用失败
+// 调用方在请求GetProvider返回的实例出错时调用，
+// 之后的GetProvider会优先选择近期失败次数更少的实例
+// 参数:
+// - name: 服务名称
+// - url: 调用失败的服务实例URL，不是name的已知实例时忽略
+func ReportFailure(name ServiceName, url string) {
+	prov.mutex.Lock()
+	defer prov.mutex.Unlock()
+	if !slices.Contains(prov.services[name], url) {
+		return
+	}
+	now := prov.clock.Now()
+	prov.failures[url] = failureRecord{count: prov.failures[url].decayed(now) + 1, at: now}
+}
+
+// leastFailing 返回urls中近期失败次数最少的实例，调用方需持有读锁
+// 失败次数取整后比较，衰减到不足一次的失败不再影响选择；
+// 权重为0的实例不参与比较，由调用方照常排除
+func (p *providers) leastFailing(urls []string) []string {
+	if len(p.failures) == 0 {
+		return urls
+	}
+	now := p.clock.Now()
+	counts := make([]int, len(urls))
+	least := -1
+	for i, u := range urls {
+		counts[i] = int(p.failures[u].decayed(now))
+		if p.weight(u) > 0 && (least < 0 || counts[i] < least) {
+			least = counts[i]
+		}
+	}
+	if least < 0 {
+		return urls
+	}
+	result := make([]string, 0, len(urls))
+	for i, u := range urls {
+		if counts[i] == least {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// PriorityKey 是Metadata中表示实例优先级的键，值为整数，数值越小越优先
+// GetProvider只在优先级数值最小的实例之间负载均衡，例如主实例为"0"、备用实例为"1"，
+// 主实例被移除、下线引流或持续失败后才会选中备用实例；未设置或无法解析时为DefaultPriority
+const PriorityKey = "priority"
+
+// DefaultPriority 是未设置优先级的实例的优先级
+const DefaultPriority = 0
+
+// priority 返回服务实例的优先级，调用方需持有读锁
+func (p *providers) priority(url string) int {
+	if v, ok := p.metadata[url][PriorityKey]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return DefaultPriority
+}
+
+// lowestPriority 返回urls中优先级数值最小的实例，调用方需持有读锁
+// 权重为0的实例不参与比较，由调用方照常排除
+func (p *providers) lowestPriority(urls []string) []string {
+	best, found := 0, false
+	for _, u := range urls {
+		if pr := p.priority(u); p.weight(u) > 0 && (!found || pr < best) {
+			best, found = pr, true
+		}
+	}
+	if !found {
+		return urls
+	}
+	result := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if p.priority(u) == best {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// weight 返回服务实例的负载均衡权重，调用方需持有读锁
+func (p *providers) weight(url string) int {
+	if w, ok := p.weights[url]; ok {
+		return w
+	}
+	return DefaultWeight
+}
+
+// rebuildRing 用name当前可路由的实例重建一致性哈希环，调用方需持有写锁
+func (p *providers) rebuildRing(name ServiceName) {
+	p.rings[name] = NewConsistentHashBalancer(DefaultHashReplicas, p.routable(p.services[name]))
+}
+
+// routable 返回urls中可以被选中的实例: 权重大于0，且不是调用方自身(见ExcludeSelf)
+// 调用方需持有锁
+func (p *providers) routable(urls []string) []string {
+	result := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if u != p.self && p.weight(u) > 0 {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// getEndpoint 根据服务名称和端点名称获取一个可用的端点URL
+// 只在声明了该端点的服务实例中随机选择，权重为0的实例和调用方自身不会被选中
+// 参数:
+// - name: 服务名称
+// - endpoint: 端点名称，为空或为DefaultEndpoint时返回服务URL
+// 返回:
+// - string: 端点URL
+// - error: 查找过程中的错误
+func (p *providers) getEndpoint(name ServiceName, endpoint string) (string, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var candidates []string
+	for _, serviceURL := range p.routable(p.services[name]) {
+		if u, ok := lookupEndpoint(serviceURL, p.endpoints[serviceURL], endpoint); ok {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no providers available for endpoint %q of service %v",
+			endpoint, name)
+	}
+	return candidates[rand.IntN(len(candidates))], nil
+}
+
+// GetProviderEndpoint 获取服务指定命名端点的URL
+// 用于同时暴露多个端口的服务，例如获取某服务的metrics端点
+// 参数:
+// - name: 服务名称
+// - endpoint: 端点名称，例如"metrics"
+// 返回:
+// - string: 端点URL
+// - error: 查找过程中的错误
+func GetProviderEndpoint(name ServiceName, endpoint string) (string, error) {
+	return prov.getEndpoint(name, endpoint)
+}
+
+// getFiltered 根据服务名称获取一个标签匹配的服务URL
+// 只在Metadata包含match中全部键值对的实例中随机选择，权重为0的实例和调用方自身不会被选中
+// 参数:
+// - name: 服务名称
+// - match: 要求的标签键值对，为空时匹配所有实例
+// 返回:
+// - string: 服务URL
+// - error: 查找过程中的错误
+func (p *providers) getFiltered(name ServiceName, match map[string]string) (string, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	var candidates []string
+	for _, serviceURL := range p.routable(p.services[name]) {
+		if matchMetadata(p.metadata[serviceURL], match) {
+			candidates = append(candidates, serviceURL)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no providers of service %v match %v", name, match)
+	}
+	return candidates[rand.IntN(len(candidates))], nil
+}
+
+// matchMetadata 判断metadata是否包含match中的全部键值对
+func matchMetadata(metadata, match map[string]string) bool {
+	for k, v := range match {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// GetProviderFiltered 获取带有指定标签的服务实例URL
+// 例如只发现version=v2的GradingService实例:
+// GetProviderFiltered(GradingService, map[string]string{"version": "v2"})
+// 参数:
+// - name: 服务名称
+// - match: 要求的标签键值对，实例须全部满足
+// 返回:
+// - string: 服务URL
+// - error: 查找过程中的错误
+func GetProviderFiltered(name ServiceName, match map[string]string) (string, error) {
+	return prov.getFiltered(name, match)
+}
+
+// getFor 根据路由键获取服务URL，相同的键总是映射到同一个实例
+// 权重为0的实例不在哈希环上，下线引流时原本映射到它的键转移到其他实例
+// 参数:
+// - name: 服务名称
+// - key: 路由键
+// 返回:
+// - string: 服务URL
+// - error: 查找过程中的错误
+func (p *providers) getFor(name ServiceName, key string) (string, error) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if ring, ok := p.rings[name]; ok {
+		if u, ok := ring.Pick(key); ok {
+			return u, nil
+		}
+	}
+	return "", fmt.Errorf("no providers available for service %v", name)
+}
+
+// GetProviderFor 使用一致性哈希为路由键选择服务实例
+// 适用于缓存等有状态后端，例如以学生ID为键，使同一学生的请求落到同一实例
+// 参数:
+// - name: 服务名称
+// - key: 路由键
+// 返回:
+// - string: 服务URL
+// - error: 查找过程中的错误
+func GetProviderFor(name ServiceName, key string) (string, error) {
+	return prov.getFor(name, key)
+}
+
+// ExcludeSelf 使GetProvider及按端点、标签、路由键的查找都排除调用方自身
+// 当服务同时是它所依赖服务类型的提供者时，可避免选中自己形成调用环路
+// 参数:
+// - selfURL: 调用方自身的ServiceURL，传入空字符串表示关闭排除
+func ExcludeSelf(selfURL string) {
+	prov.mutex.Lock()
+	defer prov.mutex.Unlock()
+	prov.self = selfURL
+	// 哈希环上也不能有调用方自身
+	for name := range prov.services {
+		prov.rebuildRing(name)
+	}
+}
+
+// exclude 返回去掉url后的新切片，不修改原切片
+func exclude(urls []string, url string) []string {
+	result := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if u != url {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+// OnProviderAdded 注册一个回调，在服务name的新实例被发现时调用
+// 例如依赖服务上线后重新建立连接
+// 回调在应用patch的goroutine中同步执行，不应长时间阻塞
+// 参数:
+// - name: 关注的服务名称
+// - cb: 回调函数，参数为新实例的URL
+func OnProviderAdded(name ServiceName, cb func(url string)) {
+	prov.mutex.Lock()
+	defer prov.mutex.Unlock()
+	prov.onAdded = withCallback(prov.onAdded, name, cb)
+}
+
+// OnProviderRemoved 注册一个回调，在服务name的实例被移除时调用
+// 回调在应用patch的goroutine中同步执行，不应长时间阻塞
+// 参数:
+// - name: 关注的服务名称
+// - cb: 回调函数，参数为被移除实例的URL
+func OnProviderRemoved(name ServiceName, cb func(url string)) {
+	prov.mutex.Lock()
+	defer prov.mutex.Unlock()
+	prov.onRemoved = withCallback(prov.onRemoved, name, cb)
+}
+
+// OnUpdate 注册一个回调，在服务name的实例集合发生变化时调用
+// 与OnProviderAdded/OnProviderRemoved不同，一个patch只触发一次回调，
+// 适合根据变化后的实例集合重新配置客户端，例如日志服务上线后切换日志输出
+// 回调在应用patch的goroutine中同步执行，不应长时间阻塞
+// 参数:
+// - name: 关注的服务名称
+// - cb: 回调函数，参数为本次新增和移除的实例URL，URL变化同时出现在两者中
+func OnUpdate(name ServiceName, cb func(added, removed []string)) {
+	prov.mutex.Lock()
+	defer prov.mutex.Unlock()
+	prov.onUpdate = withCallback(prov.onUpdate, name, cb)
+}
+
+// withCallback 返回追加了cb的新回调映射
+// 每次注册都复制映射，使Update可以在锁外安全地遍历旧映射
+func withCallback[F any](m map[ServiceName][]F, name ServiceName, cb F) map[ServiceName][]F {
+	result := make(map[ServiceName][]F, len(m)+1)
+	for k, v := range m {
+		result[k] = v
+	}
+	cbs := make([]F, 0, len(m[name])+1)
+	result[name] = append(append(cbs, m[name]...), cb)
+	return result
 }
 
 // GetProvider 是get方法的公共包装器
 // 允许外部代码获取服务URL而无需直接访问providers实例
+// 多个实例之间按轮询方式选择
 // 参数:
 // - name: 服务名称
 // 返回:
 // - string: 服务URL
 // - error: 查找过程中的错误
 func GetProvider(name ServiceName) (string, error) {
-	return prov.get(name)
+	return prov.get(name, RoundRobin)
+}
+
+// GetAllProviders 返回服务name当前已知的全部实例URL
+// 供需要自行负载均衡或向所有实例广播的调用方使用，
+// 不考虑权重和失败记录，返回的切片是副本，调用方可以随意修改
+// 参数:
+// - name: 服务名称
+// 返回:
+// - []string: 全部实例URL
+// - error: 没有可用实例时返回错误
+func GetAllProviders(name ServiceName) ([]string, error) {
+	prov.mutex.RLock()
+	defer prov.mutex.RUnlock()
+	urls := prov.services[name]
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no providers available for service %v", name)
+	}
+	return slices.Clone(urls), nil
+}
+
+// GetProviderStrategy 按指定的负载均衡策略获取服务URL
+// 参数:
+// - name: 服务名称
+// - strategy: 负载均衡策略，RoundRobin或Random
+// 返回:
+// - string: 服务URL
+// - error: 查找过程中的错误
+func GetProviderStrategy(name ServiceName, strategy Strategy) (string, error) {
+	return prov.get(name, strategy)
+}
+
+// snapshot 返回当前服务提供者缓存的深拷贝
+// 返回的映射与内部状态完全独立，调用方可以随意修改
+func (p *providers) snapshot() map[ServiceName][]string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	result := make(map[ServiceName][]string, len(p.services))
+	for name, urls := range p.services {
+		result[name] = append([]string(nil), urls...)
+	}
+	return result
+}
+
+// DumpProviders 返回某一时刻完整的服务发现状态快照
+// 用于排查服务为何无法访问其依赖，例如对比前后两次快照
+// 返回:
+// - map[ServiceName][]string: 服务名称到服务URL列表的深拷贝
+func DumpProviders() map[ServiceName][]string {
+	return prov.snapshot()
+}
+
+// ListProviders 返回当前服务提供者缓存的深拷贝，即调用方此刻认为可用的依赖服务
+// 例如门户可以据此显示已连接的日志服务
+// 返回:
+// - map[ServiceName][]string: 服务名称到服务URL列表的映射，修改它不会影响缓存
+func ListProviders() map[ServiceName][]string {
+	return prov.snapshot()
+}
+
+// ProvidersDiff 描述两次服务发现快照之间的差异
+type ProvidersDiff struct {
+	// Added 是后一次快照中新出现的服务URL
+	Added map[ServiceName][]string
+
+	// Removed 是后一次快照中消失的服务URL
+	Removed map[ServiceName][]string
+}
+
+// Empty 判断两次快照之间是否没有差异
+func (d ProvidersDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// DiffProviders 比较两次DumpProviders快照，找出新增和移除的服务URL
+// 参数:
+// - before: 较早的快照
+// - after: 较晚的快照
+// 返回:
+// - ProvidersDiff: 快照之间的差异
+func DiffProviders(before, after map[ServiceName][]string) ProvidersDiff {
+	diff := ProvidersDiff{
+		Added:   make(map[ServiceName][]string),
+		Removed: make(map[ServiceName][]string),
+	}
+	for name, urls := range after {
+		if added := missingFrom(urls, before[name]); len(added) > 0 {
+			diff.Added[name] = added
+		}
+	}
+	for name, urls := range before {
+		if removed := missingFrom(urls, after[name]); len(removed) > 0 {
+			diff.Removed[name] = removed
+		}
+	}
+	return diff
+}
+
+// missingFrom 返回urls中不存在于others里的元素
+func missingFrom(urls, others []string) []string {
+	seen := make(map[string]bool, len(others))
+	for _, u := range others {
+		seen[u] = true
+	}
+	var result []string
+	for _, u := range urls {
+		if !seen[u] {
+			result = append(result, u)
+		}
+	}
+	return result
 }
 
 // 全局providers实例，存储本地缓存的服务信息
-var prov = providers{
-	services: make(map[ServiceName][]string),
-	mutex:    new(sync.RWMutex),
+var prov = newProviders()
+
+// newProviders 创建空的服务提供者缓存
+func newProviders() providers {
+	return providers{
+		services:  make(map[ServiceName][]string),
+		endpoints: make(map[string]map[string]string),
+		metadata:  make(map[string]map[string]string),
+		weights:   make(map[string]int),
+		failures:  make(map[string]failureRecord),
+		rings:     make(map[ServiceName]*ConsistentHashBalancer),
+		misses:    make(map[ServiceName]time.Time),
+		counters:  make(map[ServiceName]*atomic.Uint64),
+		clock:     realClock{},
+		mutex:     new(sync.RWMutex),
+	}
 }
 
+// MaxPatchBytes 是服务接收的依赖更新通知请求体的大小上限
+const MaxPatchBytes = 1 << 20
+
 // serviceUpdateHandler 处理来自注册中心的服务更新通知
 // 当依赖服务发生变化时，注册中心会向此处理器发送更新
 type serviceUpdateHandler struct{}
 
 // ServeHTTP 实现http.Handler接口，处理依赖服务的更新通知
 // 业务流程:
-// 1. 验证请求方法
-// 2. 解析patch对象
-// 3. 更新本地服务提供者缓存
+// 1. 验证请求方法和Content-Type
+// 2. 读取完整请求体并解析patch对象，失败时记录发送方地址和请求体
+// 3. 校验并剔除非法条目
+// 4. 更新本地服务提供者缓存
 // 参数:
 // - w: HTTP响应写入器
 // - r: HTTP请求对象
@@ -193,17 +946,38 @@ func (suh serviceUpdateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// 解析请求体中的patch对象
-	dec := json.NewDecoder(r.Body)
-	var p patch
-	err := dec.Decode(&p)
+	// 无论请求是否合法都读完并关闭请求体，连接可以被复用
+	defer r.Body.Close()
+
+	// patch只接受JSON，未设置Content-Type的请求按JSON处理以兼容旧的发送方
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if mediaType, _, err := mime.ParseMediaType(ct); err != nil || mediaType != "application/json" {
+			log.Printf("rejected update from %v: unsupported content type %q", r.RemoteAddr, ct)
+			http.Error(w, "update must be application/json", http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	// 先读取完整的请求体，解析失败时可以记录收到的内容便于排查
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPatchBytes))
 	if err != nil {
+		log.Printf("failed to read update from %v: %v", r.RemoteAddr, err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	fmt.Printf("Update received %v\n", p)
+
+	// 解析请求体中的patch对象
+	var p patch
+	if err := json.Unmarshal(payload, &p); err != nil {
+		log.Printf("malformed update from %v: %v; body: %.512q", r.RemoteAddr, err, payload)
+		http.Error(w, "malformed patch: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("update from %v: %d added, %d removed, %d updated",
+		r.RemoteAddr, len(p.Added), len(p.Removed), len(p.Updated))
 
 	// 更新本地服务提供者缓存
+	// 先剔除名称为空或URL非法的条目，只应用合法的部分
 	// 这会更新services映射，添加新的服务URL或移除不可用的服务
-	prov.Update(p)
+	prov.Update(p.sanitize())
 }
@@ -0,0 +1,683 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// withFreshProviders 在测试期间使用空的服务提供者缓存，测试结束后恢复
+func withFreshProviders(t *testing.T) {
+	t.Helper()
+	saved := prov
+	prov = newProviders()
+	t.Cleanup(func() { prov = saved })
+}
+
+// added 返回新增一个服务实例的patch
+func added(name ServiceName, url string, metadata map[string]string) patch {
+	return patch{Added: []patchEntry{{Name: name, URL: url, Metadata: metadata}}}
+}
+
+// removed 返回移除一个服务实例的patch
+func removed(name ServiceName, url string) patch {
+	return patch{Removed: []patchEntry{{Name: name, URL: url}}}
+}
+
+func TestGetProviderPrefersLowestPriority(t *testing.T) {
+	withFreshProviders(t)
+	const primary, backup = "http://primary", "http://backup"
+	prov.Update(added(LogService, backup, map[string]string{PriorityKey: "1"}))
+	prov.Update(added(LogService, primary, map[string]string{PriorityKey: "0"}))
+
+	for i := 0; i < 10; i++ {
+		if got, err := GetProvider(LogService); err != nil || got != primary {
+			t.Fatalf("GetProvider = %q, %v; want primary %q", got, err, primary)
+		}
+	}
+
+	prov.Update(removed(LogService, primary))
+	for i := 0; i < 10; i++ {
+		if got, err := GetProvider(LogService); err != nil || got != backup {
+			t.Fatalf("after removing primary, GetProvider = %q, %v; want backup %q", got, err, backup)
+		}
+	}
+}
+
+func TestGetProviderBalancesEqualPriorityPeers(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://a", map[string]string{PriorityKey: "0"}))
+	prov.Update(added(LogService, "http://b", nil))
+	prov.Update(added(LogService, "http://backup", map[string]string{PriorityKey: "5"}))
+
+	seen := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		u, err := GetProvider(LogService)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[u]++
+	}
+	if seen["http://a"] != 5 || seen["http://b"] != 5 || seen["http://backup"] != 0 {
+		t.Fatalf("selections = %v, want a and b 5 each and backup never", seen)
+	}
+}
+
+func TestGetProviderConcurrentWithUpdates(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://a", nil))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if _, err := GetProvider(LogService); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 200; j++ {
+		url := fmt.Sprintf("http://b%d", j)
+		prov.Update(added(LogService, url, nil))
+		OnUpdate(LogService, func(added, removed []string) {})
+		ExcludeSelf(url)
+		prov.Update(removed(LogService, url))
+	}
+	wg.Wait()
+}
+
+func TestDrainedInstanceSkippedByAllLookups(t *testing.T) {
+	withFreshProviders(t)
+	zero := 0
+	prov.Update(added(LogService, "http://live", map[string]string{"zone": "a"}))
+	prov.Update(patch{Added: []patchEntry{{Name: LogService, URL: "http://drained",
+		Metadata: map[string]string{"zone": "a"}, Weight: &zero}}})
+
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprintf("student-%d", i)
+		lookups := map[string]func() (string, error){
+			"GetProviderEndpoint": func() (string, error) { return GetProviderEndpoint(LogService, "") },
+			"GetProviderFiltered": func() (string, error) {
+				return GetProviderFiltered(LogService, map[string]string{"zone": "a"})
+			},
+			"GetProviderFor": func() (string, error) { return GetProviderFor(LogService, key) },
+		}
+		for name, lookup := range lookups {
+			if got, err := lookup(); err != nil || got != "http://live" {
+				t.Fatalf("%v = %q, %v; want only the live instance", name, got, err)
+			}
+		}
+	}
+}
+
+func TestListProvidersReturnsIndependentCopy(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://log-a", nil))
+	prov.Update(added(LogService, "http://log-b", nil))
+
+	got := ListProviders()
+	if want := []string{"http://log-a", "http://log-b"}; fmt.Sprint(got[LogService]) != fmt.Sprint(want) {
+		t.Fatalf("ListProviders()[LogService] = %v, want %v", got[LogService], want)
+	}
+
+	// 修改返回值不会影响缓存
+	got[LogService][0] = "http://tampered"
+	got[GradingService] = []string{"http://injected"}
+	again := ListProviders()
+	if again[LogService][0] != "http://log-a" || len(again[GradingService]) != 0 {
+		t.Fatalf("cache changed through the returned map: %v", again)
+	}
+
+	prov.Update(removed(LogService, "http://log-a"))
+	if urls := ListProviders()[LogService]; len(urls) != 1 || urls[0] != "http://log-b" {
+		t.Fatalf("after removal ListProviders()[LogService] = %v, want [http://log-b]", urls)
+	}
+}
+
+func TestExcludeSelfAppliesToAllLookups(t *testing.T) {
+	withFreshProviders(t)
+	const self, peer = "http://self", "http://peer"
+	prov.Update(added(LogService, self, map[string]string{"zone": "a"}))
+	prov.Update(added(LogService, peer, map[string]string{"zone": "a"}))
+	ExcludeSelf(self)
+
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("student-%d", i)
+		lookups := map[string]func() (string, error){
+			"GetProvider":         func() (string, error) { return GetProvider(LogService) },
+			"GetProviderEndpoint": func() (string, error) { return GetProviderEndpoint(LogService, "") },
+			"GetProviderFiltered": func() (string, error) {
+				return GetProviderFiltered(LogService, map[string]string{"zone": "a"})
+			},
+			"GetProviderFor": func() (string, error) { return GetProviderFor(LogService, key) },
+		}
+		for name, lookup := range lookups {
+			if got, err := lookup(); err != nil || got != peer {
+				t.Fatalf("%v = %q, %v; want only the peer", name, got, err)
+			}
+		}
+	}
+}
+
+func TestInPlaceUpdateChangesAttributesWithoutCallbacks(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://log", map[string]string{"version": "v1"}))
+	calls := 0
+	OnUpdate(LogService, func(added, removed []string) { calls++ })
+
+	prov.Update(patch{Updated: []patchEntry{{Name: LogService, URL: "http://log", PrevURL: "http://log",
+		Metadata: map[string]string{"version": "v2"}}}})
+
+	if got, err := GetProviderFiltered(LogService, map[string]string{"version": "v2"}); err != nil || got != "http://log" {
+		t.Fatalf("GetProviderFiltered(version=v2) = %q, %v; want http://log", got, err)
+	}
+	if urls := ListProviders()[LogService]; len(urls) != 1 {
+		t.Fatalf("providers = %v, want the single instance", urls)
+	}
+	if calls != 0 {
+		t.Fatalf("OnUpdate called %d times for an attribute-only update, want 0", calls)
+	}
+}
+
+func TestReAddClearsStaleAttributes(t *testing.T) {
+	withFreshProviders(t)
+	zero := 0
+	prov.Update(patch{Added: []patchEntry{{Name: LogService, URL: "http://log",
+		Metadata: map[string]string{"version": "v1"}, Weight: &zero}}})
+
+	// 重新注册时不再带标签和权重，旧值应被清除
+	prov.Update(added(LogService, "http://log", nil))
+
+	if _, err := GetProviderFiltered(LogService, map[string]string{"version": "v1"}); err == nil {
+		t.Fatal("stale version=v1 tag still matches after re-registration without tags")
+	}
+	if got, err := GetProvider(LogService); err != nil || got != "http://log" {
+		t.Fatalf("GetProvider = %q, %v; want the instance back at the default weight", got, err)
+	}
+}
+
+func TestGetProviderFilteredByTags(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(GradingService, "http://v1-eu", map[string]string{"version": "v1", "region": "eu"}))
+	prov.Update(added(GradingService, "http://v2-eu", map[string]string{"version": "v2", "region": "eu"}))
+	prov.Update(added(GradingService, "http://v2-us", map[string]string{"version": "v2", "region": "us"}))
+
+	for i := 0; i < 20; i++ {
+		got, err := GetProviderFiltered(GradingService, map[string]string{"version": "v2"})
+		if err != nil || (got != "http://v2-eu" && got != "http://v2-us") {
+			t.Fatalf("filter version=v2 = %q, %v", got, err)
+		}
+	}
+	got, err := GetProviderFiltered(GradingService, map[string]string{"version": "v2", "region": "eu"})
+	if err != nil || got != "http://v2-eu" {
+		t.Fatalf("filter version=v2,region=eu = %q, %v; want http://v2-eu", got, err)
+	}
+	if _, err := GetProviderFiltered(GradingService, map[string]string{"version": "v3"}); err == nil {
+		t.Fatal("filter version=v3 matched an instance")
+	}
+}
+
+// postPatch 把body作为更新通知发送给serviceUpdateHandler，返回响应
+func postPatch(t *testing.T, body, contentType string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	serviceUpdateHandler{}.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateHandlerSkipsInvalidEntries(t *testing.T) {
+	withFreshProviders(t)
+	rec := postPatch(t, `{"Added":[
+		{"Name":"LogService","URL":""},
+		{"Name":"","URL":"http://nameless"},
+		{"Name":"LogService","URL":"::not a url"},
+		{"Name":"LogService","URL":"http://log"}]}`, "application/json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200", rec.Code)
+	}
+	if urls := ListProviders()[LogService]; fmt.Sprint(urls) != "[http://log]" {
+		t.Fatalf("LogService providers = %v, want only the valid entry", urls)
+	}
+	if urls := ListProviders()[""]; len(urls) != 0 {
+		t.Fatalf("entry without a name was applied: %v", urls)
+	}
+}
+
+func TestDiffProvidersReportsChanges(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://log-a", nil))
+	before := DumpProviders()
+
+	prov.Update(added(LogService, "http://log-b", nil))
+	prov.Update(added(GradingService, "http://grading", nil))
+	after := DumpProviders()
+
+	diff := DiffProviders(before, after)
+	if fmt.Sprint(diff.Added[LogService]) != "[http://log-b]" ||
+		fmt.Sprint(diff.Added[GradingService]) != "[http://grading]" || len(diff.Removed) != 0 {
+		t.Fatalf("diff = %+v, want log-b and grading added, nothing removed", diff)
+	}
+
+	// 快照是深拷贝，之后的变化不影响已经取得的快照
+	prov.Update(removed(LogService, "http://log-a"))
+	if fmt.Sprint(after[LogService]) != "[http://log-a http://log-b]" {
+		t.Fatalf("earlier snapshot changed: %v", after[LogService])
+	}
+	diff = DiffProviders(after, DumpProviders())
+	if fmt.Sprint(diff.Removed[LogService]) != "[http://log-a]" || len(diff.Added) != 0 {
+		t.Fatalf("diff = %+v, want only log-a removed", diff)
+	}
+	if !DiffProviders(after, after).Empty() {
+		t.Fatal("diff of a snapshot with itself is not empty")
+	}
+}
+
+func TestGetProviderEndpointResolvesNamedEndpoint(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(patch{Added: []patchEntry{{Name: GradingService, URL: "http://grading:6000",
+		Endpoints: map[string]string{"metrics": "http://grading:9090"}}}})
+	prov.Update(added(GradingService, "http://plain:6000", nil))
+
+	if got, err := GetProviderEndpoint(GradingService, "metrics"); err != nil || got != "http://grading:9090" {
+		t.Fatalf("metrics endpoint = %q, %v; want http://grading:9090", got, err)
+	}
+	// 默认端点就是ServiceURL，未声明Endpoints的实例同样可以被选中
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		got, err := GetProviderEndpoint(GradingService, DefaultEndpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[got] = true
+	}
+	if !seen["http://grading:6000"] || !seen["http://plain:6000"] || len(seen) != 2 {
+		t.Fatalf("default endpoint returned %v, want both service URLs", seen)
+	}
+	if _, err := GetProviderEndpoint(GradingService, "grpc"); err == nil {
+		t.Fatal("undeclared endpoint resolved")
+	}
+}
+
+func TestGetProviderForIsStickyPerKey(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(GradingService, "http://grading-1", nil))
+	prov.Update(added(GradingService, "http://grading-2", nil))
+
+	first, err := GetProviderFor(GradingService, "student-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if got, err := GetProviderFor(GradingService, "student-42"); err != nil || got != first {
+			t.Fatalf("GetProviderFor = %q, %v; want the same instance %q", got, err, first)
+		}
+	}
+
+	// 移除该键所在的实例后，键迁移到剩余的实例
+	prov.Update(removed(GradingService, first))
+	got, err := GetProviderFor(GradingService, "student-42")
+	if err != nil || got == first {
+		t.Fatalf("after removing %q, GetProviderFor = %q, %v; want the remaining instance", first, got, err)
+	}
+}
+
+func TestUpdatedPatchReplacesInstanceURL(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://localhost:4001", nil))
+	var events []string
+	OnProviderAdded(LogService, func(url string) { events = append(events, "+"+url) })
+	OnProviderRemoved(LogService, func(url string) { events = append(events, "-"+url) })
+
+	prov.Update(patch{Updated: []patchEntry{{Name: LogService, URL: "http://localhost:4002", PrevURL: "http://localhost:4001"}}})
+
+	if urls, err := GetAllProviders(LogService); err != nil || fmt.Sprint(urls) != "[http://localhost:4002]" {
+		t.Errorf("GetAllProviders = %v, %v; want only the new URL", urls, err)
+	}
+	if fmt.Sprint(events) != "[-http://localhost:4001 +http://localhost:4002]" {
+		t.Errorf("callbacks = %v, want the old URL removed and the new one added", events)
+	}
+}
+
+func TestProviderCallbacksFireOnAddAndRemove(t *testing.T) {
+	withFreshProviders(t)
+	var addedURLs, removedURLs, otherURLs []string
+	OnProviderAdded(LogService, func(url string) { addedURLs = append(addedURLs, url) })
+	OnProviderRemoved(LogService, func(url string) { removedURLs = append(removedURLs, url) })
+	OnProviderAdded(GradingService, func(url string) { otherURLs = append(otherURLs, url) })
+	var updates []string
+	OnUpdate(LogService, func(a, r []string) { updates = append(updates, fmt.Sprint(a, r)) })
+
+	prov.Update(added(LogService, "http://log-1", nil))
+	// 已知实例再次出现在Added中不是新发现
+	prov.Update(added(LogService, "http://log-1", nil))
+	prov.Update(removed(LogService, "http://log-1"))
+
+	if fmt.Sprint(addedURLs) != "[http://log-1]" {
+		t.Errorf("OnProviderAdded called with %v, want [http://log-1]", addedURLs)
+	}
+	if fmt.Sprint(removedURLs) != "[http://log-1]" {
+		t.Errorf("OnProviderRemoved called with %v, want [http://log-1]", removedURLs)
+	}
+	if len(otherURLs) != 0 {
+		t.Errorf("GradingService callback fired for LogService changes: %v", otherURLs)
+	}
+	if fmt.Sprint(updates) != "[[http://log-1] [] [] [http://log-1]]" {
+		t.Errorf("OnUpdate called with %v, want one call per change", updates)
+	}
+}
+
+func TestNegativeLookupCachedUntilExpiry(t *testing.T) {
+	withFreshProviders(t)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	prov.clock = clock
+
+	if _, err := GetProvider(LogService); err == nil {
+		t.Fatal("GetProvider found a provider in an empty cache")
+	}
+	// 绕过Update直接写入实例，缓存期内的查找不会重新扫描
+	prov.mutex.Lock()
+	prov.services[LogService] = []string{"http://log"}
+	prov.mutex.Unlock()
+	if _, err := GetProvider(LogService); err == nil {
+		t.Fatal("repeated miss within NegativeCacheTTL rescanned the providers")
+	}
+
+	clock.Advance(NegativeCacheTTL)
+	if got, err := GetProvider(LogService); err != nil || got != "http://log" {
+		t.Fatalf("after the miss expired, GetProvider = %q, %v; want http://log", got, err)
+	}
+}
+
+func TestNegativeLookupClearedWhenProviderAppears(t *testing.T) {
+	withFreshProviders(t)
+	prov.clock = NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	if _, err := GetProvider(LogService); err == nil {
+		t.Fatal("GetProvider found a provider in an empty cache")
+	}
+	prov.Update(added(LogService, "http://log", nil))
+	if got, err := GetProvider(LogService); err != nil || got != "http://log" {
+		t.Fatalf("after the provider was added, GetProvider = %q, %v; want http://log", got, err)
+	}
+}
+
+func TestRoundRobinCyclesThroughProviders(t *testing.T) {
+	withFreshProviders(t)
+	for _, u := range []string{"http://a", "http://b", "http://c"} {
+		prov.Update(added(LogService, u, nil))
+	}
+
+	seen := make(map[string]int)
+	var order []string
+	for i := 0; i < 6; i++ {
+		u, err := GetProviderStrategy(LogService, RoundRobin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[u]++
+		order = append(order, u)
+	}
+	if seen["http://a"] != 2 || seen["http://b"] != 2 || seen["http://c"] != 2 {
+		t.Fatalf("selections = %v, want each provider exactly twice", seen)
+	}
+	// 每一轮都按相同的顺序访问所有实例
+	if fmt.Sprint(order[:3]) != fmt.Sprint(order[3:]) {
+		t.Errorf("selection order %v does not repeat every 3 calls", order)
+	}
+}
+
+func TestRandomStrategyOnlyPicksKnownProviders(t *testing.T) {
+	withFreshProviders(t)
+	prov.Update(added(LogService, "http://a", nil))
+	prov.Update(added(LogService, "http://b", nil))
+
+	for i := 0; i < 100; i++ {
+		u, err := GetProviderStrategy(LogService, Random)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != "http://a" && u != "http://b" {
+			t.Fatalf("Random picked %q, want one of the registered providers", u)
+		}
+	}
+}
+
+func TestRegisterServiceContextCanceledMidFlight(t *testing.T) {
+	hangingRegistry(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	err := RegisterServiceContext(ctx, Registration{ServiceName: LogService, ServiceURL: "http://log"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("RegisterServiceContext = %v, want context.Canceled", err)
+	}
+}
+
+func TestShutdownServiceToleratesUnknownURL(t *testing.T) {
+	withRegistrations(t, nil)
+	captureLog(t)
+	srv := httptest.NewServer(RegistryService{})
+	defer srv.Close()
+	SetRegistryURL(srv.URL)
+	t.Cleanup(func() { SetRegistryURL("") })
+
+	if code := deregister(t, "", "http://evicted"); code != http.StatusNotFound {
+		t.Fatalf("deregistering an unknown URL returned %v, want %v", code, http.StatusNotFound)
+	}
+	if err := ShutdownService("http://evicted"); err != nil {
+		t.Errorf("ShutdownService for an already removed service = %v, want nil", err)
+	}
+}
+
+func TestShutdownServiceReportsRegistryFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	SetRegistryURL(srv.URL)
+	t.Cleanup(func() { SetRegistryURL("") })
+
+	if err := ShutdownService("http://log"); err == nil {
+		t.Error("ShutdownService succeeded against a failing registry")
+	}
+}
+
+func TestOnUpdateFiresForPostedPatch(t *testing.T) {
+	withFreshProviders(t)
+	var calls []string
+	OnUpdate(LogService, func(a, r []string) { calls = append(calls, fmt.Sprint(a, r)) })
+	OnUpdate(GradingService, func(a, r []string) { calls = append(calls, "grading") })
+
+	rec := postPatch(t, `{"Added":[
+		{"Name":"LogService","URL":"http://log-1"},
+		{"Name":"LogService","URL":"http://log-2"}]}`, "application/json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update handler returned %v", rec.Code)
+	}
+	rec = postPatch(t, `{"Removed":[{"Name":"LogService","URL":"http://log-1"}]}`, "application/json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update handler returned %v", rec.Code)
+	}
+
+	if got := fmt.Sprint(calls); got != "[[http://log-1 http://log-2] [] [] [http://log-1]]" {
+		t.Errorf("OnUpdate called with %v, want one call per posted patch", got)
+	}
+}
+
+// picked 调用n次GetProvider，返回每个实例被选中的次数
+func picked(t *testing.T, name ServiceName, n int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		url, err := GetProvider(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[url]++
+	}
+	return counts
+}
+
+func TestGetProviderFavorsHealthyInstance(t *testing.T) {
+	withFreshProviders(t)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	prov.clock = clock
+	prov.Update(added(LogService, "http://healthy", nil))
+	prov.Update(added(LogService, "http://failing", nil))
+
+	ReportFailure(LogService, "http://failing")
+	if counts := picked(t, LogService, 10); counts["http://healthy"] != 10 {
+		t.Errorf("picks after a failure = %v, want only the healthy instance", counts)
+	}
+
+	// 失败次数衰减到不足一次后恢复正常的负载分配
+	clock.Advance(4 * FailureHalfLife)
+	if counts := picked(t, LogService, 10); counts["http://failing"] == 0 {
+		t.Errorf("picks after the failure decayed = %v, want both instances", counts)
+	}
+}
+
+func TestGetProviderUsesFailingInstancesWhenAllFail(t *testing.T) {
+	withFreshProviders(t)
+	prov.clock = NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	prov.Update(added(LogService, "http://a", nil))
+	prov.Update(added(LogService, "http://b", nil))
+
+	ReportFailure(LogService, "http://a")
+	ReportFailure(LogService, "http://b")
+	if counts := picked(t, LogService, 10); counts["http://a"] == 0 || counts["http://b"] == 0 {
+		t.Errorf("picks with equal failures = %v, want both instances", counts)
+	}
+
+	// 不是该服务已知实例的URL被忽略
+	ReportFailure(LogService, "http://unknown")
+	if _, ok := prov.failures["http://unknown"]; ok {
+		t.Error("ReportFailure recorded an unknown URL")
+	}
+}
+
+func TestGetAllProvidersReturnsCopy(t *testing.T) {
+	withFreshProviders(t)
+	if _, err := GetAllProviders(LogService); err == nil {
+		t.Fatal("GetAllProviders succeeded with no registered instances")
+	}
+	for _, u := range []string{"http://a", "http://b", "http://c"} {
+		prov.Update(added(LogService, u, nil))
+	}
+
+	urls, err := GetAllProviders(LogService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(urls) != "[http://a http://b http://c]" {
+		t.Fatalf("GetAllProviders = %v, want all three instances", urls)
+	}
+
+	urls[0] = "http://mutated"
+	if again, _ := GetAllProviders(LogService); fmt.Sprint(again) != "[http://a http://b http://c]" {
+		t.Errorf("after mutating the result, GetAllProviders = %v, want the internal list unchanged", again)
+	}
+}
+
+func TestUpdateHandlerRejectsMalformedJSON(t *testing.T) {
+	withFreshProviders(t)
+	logs := captureLog(t)
+
+	rec := postPatch(t, `{"Added":[{"Name":"LogService","URL":`, "application/json")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+	// httptest.NewRequest的RemoteAddr固定为192.0.2.1:1234
+	if line := logs.String(); !strings.Contains(line, "malformed update from 192.0.2.1:1234") ||
+		!strings.Contains(line, `LogService`) {
+		t.Errorf("log = %q, want the sender and the received body", line)
+	}
+	if len(ListProviders()) != 0 {
+		t.Errorf("providers = %v after a malformed patch, want none", ListProviders())
+	}
+}
+
+func TestUpdateHandlerRejectsNonJSONContentType(t *testing.T) {
+	withFreshProviders(t)
+	captureLog(t)
+
+	rec := postPatch(t, `{"Added":[{"Name":"LogService","URL":"http://log"}]}`, "text/plain")
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusUnsupportedMediaType)
+	}
+	if len(ListProviders()) != 0 {
+		t.Errorf("providers = %v after a rejected patch, want none", ListProviders())
+	}
+}
+
+func TestUpdateHandlerAppliesValidPatch(t *testing.T) {
+	withFreshProviders(t)
+	captureLog(t)
+
+	rec := postPatch(t, `{"Added":[{"Name":"LogService","URL":"http://log-1"}]}`, "application/json; charset=utf-8")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	// 未设置Content-Type的旧发送方仍然被接受
+	rec = postPatch(t, `{"Added":[{"Name":"LogService","URL":"http://log-2"}]}`, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status without Content-Type = %v, want %v", rec.Code, http.StatusOK)
+	}
+	if urls, _ := GetAllProviders(LogService); fmt.Sprint(urls) != "[http://log-1 http://log-2]" {
+		t.Errorf("providers = %v, want both posted instances", urls)
+	}
+}
+
+func TestShutdownServiceSendsJSON(t *testing.T) {
+	type request struct{ contentType, body string }
+	requests := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{r.Header.Get("Content-Type"), string(body)}
+	}))
+	defer srv.Close()
+	SetRegistryURL(srv.URL)
+	t.Cleanup(func() { SetRegistryURL("") })
+
+	if err := ShutdownService("http://log"); err != nil {
+		t.Fatal(err)
+	}
+	got := <-requests
+	if got.contentType != "application/json" || got.body != `{"url":"http://log"}` {
+		t.Errorf("deregistration = %q %q, want a JSON url body", got.contentType, got.body)
+	}
+}
+
+func TestRemoveDropsEveryCopyOfDuplicateURL(t *testing.T) {
+	withFreshProviders(t)
+	// 绕过Update直接写入重复的URL
+	before := []string{"http://log-1", "http://log-2", "http://log-1"}
+	prov.mutex.Lock()
+	prov.services[LogService] = before
+	prov.mutex.Unlock()
+
+	prov.Update(removed(LogService, "http://log-1"))
+
+	if urls, err := GetAllProviders(LogService); err != nil || fmt.Sprint(urls) != "[http://log-2]" {
+		t.Errorf("providers = %v, %v; want both copies of http://log-1 gone", urls, err)
+	}
+	// 移除时重建切片，不在原底层数组上拼接
+	if fmt.Sprint(before) != "[http://log-1 http://log-2 http://log-1]" {
+		t.Errorf("the previous slice was modified in place: %v", before)
+	}
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 抽象了注册中心和客户端中与时间相关的操作
+// 心跳超时、过期清理、续约间隔等功能都通过Clock获取时间，
+// 测试时可注入FakeClock手动推进时间，使结果可重复
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+
+	// After 在经过d之后向返回的通道发送当前时间
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock 是Clock的默认实现，直接使用time包
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SetClock 替换注册中心和客户端使用的Clock
+// 参数:
+// - c: 新的Clock，传入nil时恢复为真实时钟
+func SetClock(c Clock) {
+	if c == nil {
+		c = realClock{}
+	}
+	reg.mu.Lock()
+	reg.clock = c
+	reg.mu.Unlock()
+
+	prov.mutex.Lock()
+	prov.clock = c
+	prov.mutex.Unlock()
+}
+
+// FakeClock 是用于测试的Clock实现
+// 时间只会在调用Advance时前进，到期的After通道随之触发
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+// fakeWaiter 是一个等待到期的After调用
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock 创建一个从now开始的FakeClock
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now 返回FakeClock的当前时间
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After 返回一个在FakeClock前进d之后触发的通道
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance 将时间推进d，并按到期顺序触发所有到期的After通道
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+
+	sort.Slice(c.waiters, func(i, j int) bool {
+		return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+	})
+	pending := c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.deadline.After(c.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- c.now
+	}
+	c.waiters = pending
+}
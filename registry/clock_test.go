@@ -0,0 +1,50 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+// waitForWaiters 等待c上至少有n个未到期的After调用，避免在等待方就绪前推进时间
+func waitForWaiters(t *testing.T, c *FakeClock, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.mu.Lock()
+		pending := len(c.waiters)
+		c.mu.Unlock()
+		if pending >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("FakeClock has %d pending After calls, want %d", pending, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClockFiresOnlyWhenAdvanced(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	ch := c.After(time.Minute)
+
+	c.Advance(59 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("After(1m) fired after advancing 59s")
+	default:
+	}
+
+	c.Advance(time.Second)
+	select {
+	case at := <-ch:
+		if want := start.Add(time.Minute); !at.Equal(want) {
+			t.Errorf("After fired with %v, want %v", at, want)
+		}
+	default:
+		t.Fatal("After(1m) did not fire after advancing 1m")
+	}
+	if got := c.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("Now = %v, want %v", got, start.Add(time.Minute))
+	}
+}
@@ -0,0 +1,114 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHeartbeatInterval 是心跳检查的默认间隔
+	DefaultHeartbeatInterval = 15 * time.Second
+
+	// DefaultHeartbeatThreshold 是剔除服务前允许的连续失败次数
+	DefaultHeartbeatThreshold = 3
+
+	// heartbeatTimeout 是单次心跳请求的超时时间
+	heartbeatTimeout = 5 * time.Second
+)
+
+// RunHeartbeat 周期性地检查所有已注册服务的健康状态
+// 服务崩溃时不会调用ShutdownService，只能由注册中心主动发现并剔除
+// 业务流程:
+// 1. 每隔interval向每个服务的/health发送GET请求
+// 2. 记录每个服务连续失败的次数，成功一次即清零
+// 3. 连续失败达到threshold次时移除该服务，并通知依赖方
+// 此函数会阻塞直到ctx被取消，通常在单独的goroutine中运行
+// 参数:
+// - ctx: 控制心跳检查生命周期的上下文
+// - interval: 检查间隔
+// - threshold: 剔除前允许的连续失败次数
+func RunHeartbeat(ctx context.Context, interval time.Duration, threshold int) {
+	reg.heartbeat(ctx, interval, threshold)
+}
+
+// heartbeat 是RunHeartbeat的实现
+func (r *registry) heartbeat(ctx context.Context, interval time.Duration, threshold int) {
+	failures := make(map[string]int)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.clock.After(interval):
+		}
+
+		// 在读锁下复制注册表，检查过程中不持有锁
+		r.mu.RLock()
+		regs := append([]Registration(nil), r.registrations...)
+		r.mu.RUnlock()
+
+		var (
+			mu sync.Mutex
+			wg sync.WaitGroup
+		)
+		registered := make(map[string]bool, len(regs))
+		for _, reg := range regs {
+			registered[reg.ServiceURL] = true
+			wg.Add(1)
+			go func(reg Registration) {
+				defer wg.Done()
+				err := checkHealth(ctx, reg.ServiceURL)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err == nil {
+					delete(failures, reg.ServiceURL)
+					return
+				}
+				failures[reg.ServiceURL]++
+				log.Printf("heartbeat to %v at %v failed (%v/%v): %v",
+					reg.ServiceName, reg.ServiceURL, failures[reg.ServiceURL], threshold, err)
+			}(reg)
+		}
+		wg.Wait()
+
+		for url, n := range failures {
+			// 已经注销的服务不再跟踪
+			if !registered[url] {
+				delete(failures, url)
+				continue
+			}
+			if n >= threshold {
+				delete(failures, url)
+				if err := r.remove(url, ReasonEvicted); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
+}
+
+// checkHealth 请求服务的/health接口
+// 返回:
+// - error: 请求失败或响应码不是200时返回错误
+func checkHealth(ctx context.Context, serviceURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
+	defer cancel()
+
+	req, err := newRequest(ctx, http.MethodGet, serviceURL+"/health", "", nil)
+	if err != nil {
+		return err
+	}
+	res, err := do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check responded with code %v", res.StatusCode)
+	}
+	return nil
+}
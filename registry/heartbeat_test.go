@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatEvictionRecordsReason(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 2)
+	dependent := newPatchRecorder(t)
+	// 依赖方自身保持健康，且探测它不依赖DNS解析
+	healthy := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer healthy.Close()
+	r.registrations = []Registration{
+		{ServiceName: LogService, ServiceURL: unreachableURL(t)},
+		{ServiceName: GradingService, ServiceURL: healthy.URL, ServiceUpdateURL: dependent.URL,
+			RequireServices: []ServiceName{LogService}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.heartbeat(ctx, time.Millisecond, 1)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(removalReasons(dependent)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("unreachable service was not evicted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := fmt.Sprint(removalReasons(dependent)); got != "[evicted]" {
+		t.Errorf("dependent received removal reasons %v, want [evicted]", got)
+	}
+}
+
+func TestHeartbeatEvictsOnFakeClockTicks(t *testing.T) {
+	const interval, threshold = 15 * time.Second, 2
+	r := newTestRegistry(httpNotifier{}, 2)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	r.clock = clock
+	// 同步推送，剔除完成时依赖方已经收到通知
+	r.synchronousNotify = true
+	dependent := newPatchRecorder(t)
+	r.registrations = []Registration{
+		{ServiceName: LogService, ServiceURL: unreachableURL(t)},
+		{ServiceName: GradingService, ServiceURL: healthyService(t), ServiceUpdateURL: dependent.URL,
+			RequireServices: []ServiceName{LogService}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.heartbeat(ctx, interval, threshold)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// 每次推进interval触发一轮检查，下一次After调用出现时上一轮已经结束
+	for i := 0; i < threshold-1; i++ {
+		waitForWaiters(t, clock, 1)
+		clock.Advance(interval)
+	}
+	waitForWaiters(t, clock, 1)
+	r.mu.RLock()
+	remaining := len(r.registrations)
+	r.mu.RUnlock()
+	if remaining != 2 {
+		t.Fatalf("service evicted after %d failed checks, want eviction only at %d", threshold-1, threshold)
+	}
+
+	clock.Advance(interval)
+	waitForWaiters(t, clock, 1)
+	if got := fmt.Sprint(removalReasons(dependent)); got != "[evicted]" {
+		t.Errorf("dependent received removal reasons %v, want [evicted]", got)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.registrations) != 1 {
+		t.Errorf("registrations after eviction = %v, want only the healthy dependent", r.registrations)
+	}
+}
+
+func TestHeartbeatEvictsServiceThatStopsResponding(t *testing.T) {
+	const interval, threshold = time.Second, 3
+	var healthy atomic.Bool
+	healthy.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	r := newTestRegistry(httpNotifier{}, 2)
+	clock := NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	r.clock = clock
+	r.synchronousNotify = true
+	dependent := newPatchRecorder(t)
+	r.registrations = []Registration{
+		{ServiceName: LogService, ServiceURL: srv.URL},
+		{ServiceName: GradingService, ServiceURL: healthyService(t), ServiceUpdateURL: dependent.URL,
+			RequireServices: []ServiceName{LogService}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.heartbeat(ctx, interval, threshold)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	tick := func() {
+		waitForWaiters(t, clock, 1)
+		clock.Advance(interval)
+		waitForWaiters(t, clock, 1)
+	}
+	registered := func() int {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return len(r.registrations)
+	}
+
+	// 失败次数未达到阈值时恢复健康，计数清零
+	healthy.Store(false)
+	for i := 0; i < threshold-1; i++ {
+		tick()
+	}
+	healthy.Store(true)
+	tick()
+	healthy.Store(false)
+	for i := 0; i < threshold-1; i++ {
+		tick()
+	}
+	if n := registered(); n != 2 {
+		t.Fatalf("service evicted without %d consecutive failures", threshold)
+	}
+
+	tick()
+	if n := registered(); n != 1 {
+		t.Fatalf("registry holds %d registrations after %d consecutive failures, want 1", n, threshold)
+	}
+	if got := fmt.Sprint(removalReasons(dependent)); got != "[evicted]" {
+		t.Errorf("dependent received removal reasons %v, want [evicted]", got)
+	}
+}
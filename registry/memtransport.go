@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// InMemoryTransport 是进程内的服务发现传输层
+// 注册中心与客户端之间的消息通过直接函数调用传递而不经过TCP，
+// 使集成测试无需绑定端口，运行更快且结果确定
+// 使用方式:
+// 1. 通过SetNotifier把它设置为注册中心的Notifier
+// 2. 通过HandleClient为每个服务的ServiceUpdateURL挂载更新处理
+// 3. 通过Register/Deregister代替HTTP注册与注销
+type InMemoryTransport struct {
+	// handlers 是更新端点URL到处理函数的映射
+	handlers map[string]func(payload []byte) error
+
+	mu sync.RWMutex
+}
+
+// NewInMemoryTransport 创建一个空的进程内传输层
+func NewInMemoryTransport() *InMemoryTransport {
+	return &InMemoryTransport{
+		handlers: make(map[string]func(payload []byte) error),
+	}
+}
+
+// Handle 在updateURL上挂载自定义的更新处理函数
+// 参数:
+// - updateURL: 服务的ServiceUpdateURL
+// - h: 接收JSON编码patch的处理函数
+func (t *InMemoryTransport) Handle(updateURL string, h func(payload []byte) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlers[updateURL] = h
+}
+
+// HandleClient 在updateURL上挂载客户端的标准更新处理
+// 收到的patch与HTTP路径一样经过校验后应用到本地服务提供者缓存
+// 参数:
+// - updateURL: 服务的ServiceUpdateURL
+func (t *InMemoryTransport) HandleClient(updateURL string) {
+	t.Handle(updateURL, func(payload []byte) error {
+		var p patch
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		prov.Update(p.sanitize())
+		return nil
+	})
+}
+
+// Notify 实现Notifier接口，把patch直接交给updateURL上挂载的处理函数
+func (t *InMemoryTransport) Notify(ctx context.Context, url string, payload []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t.mu.RLock()
+	h, ok := t.handlers[url]
+	t.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no in-memory handler for update URL %v", url)
+	}
+	return h(payload)
+}
+
+// Register 在进程内向注册中心注册服务，等价于RegisterService的HTTP请求
+// 参数:
+// - r: 服务注册信息
+// 返回:
+// - error: 注册过程中的错误
+func (t *InMemoryTransport) Register(r Registration) error {
+	return reg.add(r)
+}
+
+// Deregister 在进程内从注册中心注销服务，等价于ShutdownService的HTTP请求
+// 参数:
+// - url: 要注销的服务URL
+// 返回:
+// - error: 注销过程中的错误
+func (t *InMemoryTransport) Deregister(url string) error {
+	// 与ShutdownService一致，服务已不在注册表中时视为注销成功
+	if err := reg.remove(url, ReasonShutdown); err != nil && !errors.Is(err, ErrServiceNotFound) {
+		return err
+	}
+	return nil
+}
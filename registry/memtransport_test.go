@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"context"
+	"testing"
+)
+
+func TestInMemoryTransportPropagatesDependencies(t *testing.T) {
+	withRegistrations(t, nil)
+	withFreshProviders(t)
+	tr := NewInMemoryTransport()
+	SetNotifier(tr)
+	SetSynchronousNotify(true)
+	t.Cleanup(func() {
+		SetNotifier(nil)
+		SetSynchronousNotify(false)
+	})
+
+	grading := Registration{
+		ServiceName:      GradingService,
+		ServiceURL:       "mem://grading",
+		ServiceUpdateURL: "mem://grading/services",
+		RequireServices:  []ServiceName{LogService},
+	}
+	logService := Registration{
+		ServiceName:      LogService,
+		ServiceURL:       "mem://log",
+		ServiceUpdateURL: "mem://log/services",
+	}
+	tr.HandleClient(grading.ServiceUpdateURL)
+	tr.HandleClient(logService.ServiceUpdateURL)
+
+	if err := tr.Register(grading); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetProvider(LogService); err == nil {
+		t.Fatal("GetProvider found LogService before it registered")
+	}
+
+	if err := tr.Register(logService); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetProvider(LogService); err != nil || got != logService.ServiceURL {
+		t.Fatalf("after LogService registered, GetProvider = %q, %v; want %q", got, err, logService.ServiceURL)
+	}
+
+	if err := tr.Deregister(logService.ServiceURL); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetProvider(LogService); err == nil {
+		t.Fatalf("after LogService deregistered, GetProvider = %q, want an error", got)
+	}
+	// 重复注销视为成功
+	if err := tr.Deregister(logService.ServiceURL); err != nil {
+		t.Errorf("second Deregister returned %v, want nil", err)
+	}
+}
+
+func TestInMemoryTransportUnknownUpdateURL(t *testing.T) {
+	tr := NewInMemoryTransport()
+	if err := tr.Notify(context.Background(), "mem://nowhere/services", []byte(`{}`)); err == nil {
+		t.Error("Notify to an unmounted update URL succeeded, want an error")
+	}
+}
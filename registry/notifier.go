@@ -0,0 +1,44 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Notifier 负责把序列化后的patch送达服务的更新端点
+// 默认实现通过HTTP POST发送；测试中可替换为进程内实现，避免占用端口
+type Notifier interface {
+	// Notify 将payload(JSON编码的patch)发送到url对应的更新端点
+	// 端点未能成功处理时返回错误
+	Notify(ctx context.Context, url string, payload []byte) error
+}
+
+// httpNotifier 是Notifier的默认实现，通过HTTP POST推送patch
+type httpNotifier struct{}
+
+// Notify 向更新端点POST patch，响应码不是200时返回错误
+func (httpNotifier) Notify(ctx context.Context, url string, payload []byte) error {
+	res, err := post(ctx, url, "application/json", bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("update endpoint %v responded with code %v", url, res.StatusCode)
+	}
+	return nil
+}
+
+// SetNotifier 替换注册中心推送依赖更新所使用的Notifier
+// 参数:
+// - n: 新的Notifier，传入nil时恢复默认的HTTP实现
+func SetNotifier(n Notifier) {
+	if n == nil {
+		n = httpNotifier{}
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.notifier = n
+}
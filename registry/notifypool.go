@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"runtime"
+	"sync"
+)
+
+// notifyPool 是执行依赖推送的工作池
+// 任务进入无界队列，提交方(例如处理POST /services的add)从不阻塞；
+// 工作goroutine按需启动，数量不超过size，队列清空后退出
+type notifyPool struct {
+	// mu 保护以下所有字段
+	mu sync.Mutex
+
+	// size 是同时运行的工作goroutine上限，修改后立即生效
+	size int
+
+	// running 是当前运行的工作goroutine数量
+	running int
+
+	// queue 是待执行的推送任务
+	queue []func()
+}
+
+// newNotifyPool 创建工作池
+// 参数:
+// - size: 工作goroutine数量的上限
+func newNotifyPool(size int) *notifyPool {
+	return &notifyPool{size: max(size, 1)}
+}
+
+// submit 提交一个推送任务后立即返回
+// 运行中的工作goroutine少于size时启动一个新的
+func (p *notifyPool) submit(job func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = append(p.queue, job)
+	if p.running < p.size {
+		p.running++
+		go p.work()
+	}
+}
+
+// work 依次执行队列中的任务
+// 队列为空、或工作goroutine数量超过调小后的size时退出
+func (p *notifyPool) work() {
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 || p.running > p.size {
+			p.running--
+			p.mu.Unlock()
+			return
+		}
+		job := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		job()
+	}
+}
+
+// setSize 修改工作goroutine数量的上限
+// 调大时为排队的任务补充工作goroutine，调小时多余的工作goroutine在完成当前任务后退出
+func (p *notifyPool) setSize(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.size = max(n, 1)
+	for p.running < p.size && p.running < len(p.queue) {
+		p.running++
+		go p.work()
+	}
+}
+
+// SetNotifyWorkers 设置并发执行依赖推送的工作goroutine数量上限，默认为runtime.NumCPU()
+// 可以在任何时候调用，修改立即生效
+// 参数:
+// - n: 工作goroutine数量上限，小于1时按1处理
+func SetNotifyWorkers(n int) {
+	reg.pool.setSize(n)
+}
+
+// defaultNotifyWorkers 是工作池的默认大小
+var defaultNotifyWorkers = runtime.NumCPU()
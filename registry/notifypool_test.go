@@ -0,0 +1,123 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// notifierFunc 把普通函数适配为Notifier
+type notifierFunc func(ctx context.Context, url string, payload []byte) error
+
+func (f notifierFunc) Notify(ctx context.Context, url string, payload []byte) error {
+	return f(ctx, url, payload)
+}
+
+// newTestRegistry 创建与全局reg相互独立的注册中心，测试之间不共享状态
+func newTestRegistry(n Notifier, workers int) *registry {
+	return &registry{
+		mu:           new(sync.RWMutex),
+		ctx:          context.Background(),
+		notifier:     n,
+		clock:        realClock{},
+		sendAttempts: 1,
+		pool:         newNotifyPool(workers),
+	}
+}
+
+// dependents 返回n个依赖LogService的注册信息
+func dependents(n int) []Registration {
+	regs := make([]Registration, n)
+	for i := range regs {
+		url := fmt.Sprintf("http://dependent-%d", i)
+		regs[i] = Registration{
+			ServiceName:      GradingService,
+			ServiceURL:       url,
+			ServiceUpdateURL: url + "/services",
+			RequireServices:  []ServiceName{LogService},
+		}
+	}
+	return regs
+}
+
+func TestNotifyPoolBoundsConcurrency(t *testing.T) {
+	const workers = 4
+	var inFlight, peak, calls atomic.Int64
+	r := newTestRegistry(notifierFunc(func(context.Context, string, []byte) error {
+		n := inFlight.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		inFlight.Add(-1)
+		calls.Add(1)
+		return nil
+	}), workers)
+	r.synchronousNotify = true
+
+	r.notifyRegistrations(dependents(100), patch{
+		Added: []patchEntry{{Name: LogService, URL: "http://log"}},
+	})
+
+	if got := calls.Load(); got != 100 {
+		t.Fatalf("notified %d dependents, want 100", got)
+	}
+	if got := peak.Load(); got > workers {
+		t.Fatalf("peak concurrent notifications = %d, want at most %d", got, workers)
+	}
+}
+
+func TestNotifyPoolSubmitDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	r := newTestRegistry(notifierFunc(func(context.Context, string, []byte) error {
+		<-release
+		return nil
+	}), 1)
+	defer close(release)
+
+	done := make(chan struct{})
+	go func() {
+		r.notifyRegistrations(dependents(3), patch{
+			Added: []patchEntry{{Name: LogService, URL: "http://log"}},
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notifyRegistrations blocked on a busy worker pool")
+	}
+}
+
+func TestSetSizeAfterStart(t *testing.T) {
+	p := newNotifyPool(1)
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(3)
+	for i := 0; i < 3; i++ {
+		p.submit(func() {
+			started.Done()
+			<-release
+		})
+	}
+
+	// 调大上限后排队的任务立即获得工作goroutine
+	p.setSize(3)
+	waited := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("queued jobs did not start after raising the pool size")
+	}
+	close(release)
+}
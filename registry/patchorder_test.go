@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedPatch 是依赖方收到的一次更新通知及其到达时间
+type recordedPatch struct {
+	At    time.Time
+	Patch patch
+}
+
+// patchRecorder 是记录所收到patch的更新端点，供测试断言通知的顺序和时间
+type patchRecorder struct {
+	// URL 是更新端点的地址，用作注册信息的ServiceUpdateURL
+	URL string
+
+	mu      sync.Mutex
+	patches []recordedPatch
+}
+
+// newPatchRecorder 启动记录更新通知的httptest服务器，测试结束时关闭
+func newPatchRecorder(t *testing.T) *patchRecorder {
+	t.Helper()
+	rec := new(patchRecorder)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p patch
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.patches = append(rec.patches, recordedPatch{At: time.Now(), Patch: p})
+	}))
+	t.Cleanup(srv.Close)
+	rec.URL = srv.URL
+	return rec
+}
+
+// Patches 返回目前为止收到的全部patch，按到达顺序排列
+func (rec *patchRecorder) Patches() []recordedPatch {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]recordedPatch(nil), rec.patches...)
+}
+
+// describe 把patch概括为"+url"、"-url"、"~prev>url"形式的序列，便于比较
+func describe(p patch) []string {
+	var events []string
+	for _, e := range p.Added {
+		events = append(events, "+"+e.URL)
+	}
+	for _, e := range p.Removed {
+		events = append(events, "-"+e.URL)
+	}
+	for _, e := range p.Updated {
+		events = append(events, fmt.Sprintf("~%v>%v", e.PrevURL, e.URL))
+	}
+	return events
+}
+
+func TestPatchOrderingAcrossRegistrationLifecycle(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 4)
+	// 同步推送，每个注册操作返回时通知已经送达
+	r.synchronousNotify = true
+
+	logSink := newPatchRecorder(t)
+	logService := func(url string) Registration {
+		return Registration{ServiceName: LogService, ServiceURL: url, ServiceUpdateURL: logSink.URL}
+	}
+	dependent := newPatchRecorder(t)
+	grading := Registration{
+		ServiceName:      GradingService,
+		ServiceURL:       "http://grading",
+		ServiceUpdateURL: dependent.URL,
+		RequireServices:  []ServiceName{LogService},
+	}
+
+	steps := []func() error{
+		func() error { return r.add(logService("http://log-a")) },
+		func() error { return r.add(grading) },
+		func() error { return r.add(logService("http://log-b")) },
+		func() error { return r.remove("http://log-a", ReasonShutdown) },
+		func() error { return r.remove("http://grading", ReasonShutdown) },
+		// 依赖方注销后不再收到任何通知
+		func() error { return r.add(logService("http://log-c")) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	got := dependent.Patches()
+	want := [][]string{
+		{"+http://log-a"},
+		{"+http://log-b"},
+		{"-http://log-a"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("dependent received %d patches, want %d: %+v", len(got), len(want), got)
+	}
+	for i, rp := range got {
+		if fmt.Sprint(describe(rp.Patch)) != fmt.Sprint(want[i]) {
+			t.Errorf("patch %d = %v, want %v", i, describe(rp.Patch), want[i])
+		}
+		if i > 0 && rp.At.Before(got[i-1].At) {
+			t.Errorf("patch %d arrived at %v, before patch %d at %v", i, rp.At, i-1, got[i-1].At)
+		}
+	}
+	if reason := got[2].Patch.Removed[0].Reason; reason != ReasonShutdown {
+		t.Errorf("removal reason = %q, want %q", reason, ReasonShutdown)
+	}
+}
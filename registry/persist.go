@@ -0,0 +1,162 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultPersistInterval 是注册表写入磁盘的默认间隔
+const DefaultPersistInterval = 10 * time.Second
+
+// DefaultSnapshotMaxAge 是从状态文件恢复注册信息时允许的最大年龄
+// 注册中心离线超过此时长后，保存的条目即使能通过健康检查也不再恢复
+const DefaultSnapshotMaxAge = time.Hour
+
+// persistMu 保证同一时间只有一个goroutine在写状态文件
+var persistMu sync.Mutex
+
+// persistedRegistration 是状态文件中的一个条目
+type persistedRegistration struct {
+	Registration
+
+	// LastSeen 是注册中心最后一次确认该服务在注册表中的时间，即写入状态文件的时间
+	// 旧版本写入的状态文件没有此字段
+	LastSeen time.Time `json:",omitempty"`
+}
+
+// SetSnapshotMaxAge 设置从状态文件恢复注册信息时允许的最大年龄，默认为DefaultSnapshotMaxAge
+// 应在Load之前调用
+// 参数:
+// - d: 最大年龄，小于等于0表示不限制
+func SetSnapshotMaxAge(d time.Duration) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.snapshotMaxAge = d
+}
+
+// save 将当前注册表以JSON格式写入path
+// 先写入临时文件再重命名，避免写到一半时进程退出留下损坏的文件
+// 参数:
+// - path: 状态文件路径
+// 返回:
+// - error: 序列化或写文件过程中的错误
+func (r *registry) save(path string) error {
+	r.mu.RLock()
+	now := r.clock.Now()
+	entries := make([]persistedRegistration, len(r.registrations))
+	for i, reg := range r.registrations {
+		entries[i] = persistedRegistration{Registration: reg, LastSeen: now}
+	}
+	r.mu.RUnlock()
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	persistMu.Lock()
+	defer persistMu.Unlock()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// load 从path读取注册表
+// 注册中心离线期间服务可能已经停止，所以每个条目都要先通过健康检查才会被恢复，
+// LastSeen早于snapshotMaxAge的条目直接丢弃；没有LastSeen的旧条目只做健康检查
+// 参数:
+// - path: 状态文件路径，文件不存在时视为空注册表
+// 返回:
+// - error: 读文件或反序列化过程中的错误
+func (r *registry) load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []persistedRegistration
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	maxAge, now := r.snapshotMaxAge, r.clock.Now()
+	r.mu.RUnlock()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		restored = make([]Registration, 0, len(saved))
+	)
+	for _, entry := range saved {
+		if maxAge > 0 && !entry.LastSeen.IsZero() && now.Sub(entry.LastSeen) > maxAge {
+			log.Printf("not restoring %v at %v: last seen %v ago",
+				entry.ServiceName, entry.ServiceURL, now.Sub(entry.LastSeen).Round(time.Second))
+			continue
+		}
+		wg.Add(1)
+		go func(reg Registration) {
+			defer wg.Done()
+			if err := checkHealth(r.ctx, reg.ServiceURL); err != nil {
+				log.Printf("not restoring %v at %v: %v", reg.ServiceName, reg.ServiceURL, err)
+				return
+			}
+			mu.Lock()
+			restored = append(restored, reg)
+			mu.Unlock()
+		}(entry.Registration)
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	r.registrations = append(r.registrations, restored...)
+	r.mu.Unlock()
+	log.Printf("restored %v of %v registrations from %v", len(restored), len(saved), path)
+	return nil
+}
+
+// Load 从状态文件恢复注册表，应在注册中心开始接收请求之前调用
+// 只有不超过最大年龄(见SetSnapshotMaxAge)且仍能通过健康检查的服务会被恢复
+// 参数:
+// - path: 状态文件路径，文件不存在时不做任何操作
+// 返回:
+// - error: 读取或解析状态文件过程中的错误
+func Load(path string) error {
+	return reg.load(path)
+}
+
+// RunPersistence 周期性地将注册表写入path，ctx取消时再写入一次后返回
+// 此函数会阻塞，通常在单独的goroutine中运行
+// 参数:
+// - ctx: 控制持久化生命周期的上下文
+// - path: 状态文件路径
+// - interval: 写入间隔
+func RunPersistence(ctx context.Context, path string, interval time.Duration) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		log.Println(err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if err := reg.save(path); err != nil {
+				log.Println(err)
+			}
+			return
+		case <-reg.clock.After(interval):
+			if err := reg.save(path); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// healthyService 启动一个/health总是返回200的服务，返回其URL
+func healthyService(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestLoadDropsStaleEntries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	fresh, stale := healthyService(t), healthyService(t)
+	entries := []persistedRegistration{
+		{
+			Registration: Registration{ServiceName: LogService, ServiceURL: fresh, ServiceUpdateURL: fresh + "/services"},
+			LastSeen:     now.Add(-time.Minute),
+		},
+		{
+			Registration: Registration{ServiceName: LogService, ServiceURL: stale, ServiceUpdateURL: stale + "/services"},
+			LastSeen:     now.Add(-2 * time.Hour),
+		},
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "registry.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestRegistry(nil, 1)
+	r.clock = NewFakeClock(now)
+	r.snapshotMaxAge = time.Hour
+	if err := r.load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.registrations) != 1 || r.registrations[0].ServiceURL != fresh {
+		t.Fatalf("restored %v, want only the fresh entry %v", r.registrations, fresh)
+	}
+}
+
+func TestSaveRecordsLastSeen(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestRegistry(nil, 1)
+	r.clock = NewFakeClock(now)
+	r.registrations = []Registration{{ServiceName: LogService, ServiceURL: "http://log"}}
+
+	path := filepath.Join(t.TempDir(), "registry.json")
+	if err := r.save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []persistedRegistration
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !entries[0].LastSeen.Equal(now) {
+		t.Fatalf("saved %+v, want one entry last seen at %v", entries, now)
+	}
+}
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	logURL, gradingURL := healthyService(t), healthyService(t)
+	saved := newTestRegistry(nil, 1)
+	saved.registrations = []Registration{
+		{ServiceName: LogService, ServiceURL: logURL, ServiceUpdateURL: logURL + "/services"},
+		{ServiceName: GradingService, ServiceURL: gradingURL, ServiceUpdateURL: gradingURL + "/services",
+			RequireServices: []ServiceName{LogService}, Metadata: map[string]string{"zone": "a"}},
+	}
+	path := filepath.Join(t.TempDir(), "registry.json")
+	if err := saved.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟注册中心重启：全新的注册中心从状态文件恢复
+	loaded := newTestRegistry(nil, 1)
+	if err := loaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.registrations) != 2 {
+		t.Fatalf("loaded %d registrations, want 2", len(loaded.registrations))
+	}
+	byURL := make(map[string]Registration)
+	for _, r := range loaded.registrations {
+		byURL[r.ServiceURL] = r
+	}
+	for _, want := range saved.registrations {
+		if got := byURL[want.ServiceURL]; !reflect.DeepEqual(got, want) {
+			t.Errorf("loaded %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestLoadSkipsUnhealthyEntries(t *testing.T) {
+	alive := healthyService(t)
+	saved := newTestRegistry(nil, 1)
+	saved.registrations = []Registration{
+		{ServiceName: LogService, ServiceURL: alive},
+		{ServiceName: LogService, ServiceURL: unreachableURL(t)},
+	}
+	path := filepath.Join(t.TempDir(), "registry.json")
+	if err := saved.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newTestRegistry(nil, 1)
+	if err := loaded.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.registrations) != 1 || loaded.registrations[0].ServiceURL != alive {
+		t.Fatalf("loaded %v, want only the healthy service %v", loaded.registrations, alive)
+	}
+}
+
+func TestLoadMissingFileIsEmpty(t *testing.T) {
+	r := newTestRegistry(nil, 1)
+	if err := r.load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Fatalf("load of a missing file = %v, want nil", err)
+	}
+	if len(r.registrations) != 0 {
+		t.Errorf("registrations = %v, want none", r.registrations)
+	}
+}
@@ -1,5 +1,11 @@
 package registry
 
+import (
+	"fmt"
+	"log"
+	"net/url"
+)
+
 // Registration 结构体定义了服务注册所需的信息
 // 每个微服务在注册时都需要提供这些信息，它是服务注册与发现的核心数据结构
 type Registration struct {
@@ -19,6 +25,93 @@ type Registration struct {
 	// 注册中心通过向此URL发送POST请求通知服务其依赖的变化
 	// 例如：http://localhost:6000/services
 	ServiceUpdateURL string
+
+	// Endpoints 是可选的命名端点集合，用于暴露多个端口的服务
+	// 例如：{"http": "http://localhost:6000", "metrics": "http://localhost:6001"}
+	// ServiceURL 始终作为默认端点，未声明Endpoints的服务不受影响
+	Endpoints map[string]string `json:",omitempty"`
+
+	// InstanceID 是服务实例可选的稳定标识
+	// 服务重启后即使端口(URL)变化，注册中心也能据此识别为同一实例，
+	// 原地更新其URL并只向依赖方发送一条更新通知
+	InstanceID string `json:",omitempty"`
+
+	// Metadata 是可选的服务标签，例如{"region": "cn-east", "version": "v2"}
+	// 依赖方可以通过GetProviderFiltered只发现带有指定标签的实例
+	Metadata map[string]string `json:",omitempty"`
+
+	// Weight 是实例的负载均衡权重，请求按权重比例分配到各实例
+	// 未设置时视为1；设置为0表示下线引流(drain)，不再向该实例路由请求
+	Weight *int `json:",omitempty"`
+}
+
+// entry 将注册信息转换为patch条目
+func (r Registration) entry() patchEntry {
+	return patchEntry{
+		Name:      r.ServiceName,
+		URL:       r.ServiceURL,
+		Endpoints: r.Endpoints,
+		Metadata:  r.Metadata,
+		Weight:    r.Weight,
+	}
+}
+
+// DefaultWeight 是未设置Weight的实例的权重
+const DefaultWeight = 1
+
+// weightOf 返回实例的有效权重，未设置时为DefaultWeight，负数按0处理
+func weightOf(w *int) int {
+	if w == nil {
+		return DefaultWeight
+	}
+	return max(*w, 0)
+}
+
+// Validate 校验注册信息是否可以被注册中心接受
+// 服务名称不能为空，ServiceURL和ServiceUpdateURL必须是包含协议和主机的绝对地址
+// 返回:
+// - error: 校验失败的原因
+func (r Registration) Validate() error {
+	if r.ServiceName == "" {
+		return fmt.Errorf("registration has empty service name (URL: %q)", r.ServiceURL)
+	}
+	if err := checkAbsoluteURL(r.ServiceURL); err != nil {
+		return fmt.Errorf("registration for %v has invalid ServiceURL: %v", r.ServiceName, err)
+	}
+	if err := checkAbsoluteURL(r.ServiceUpdateURL); err != nil {
+		return fmt.Errorf("registration for %v has invalid ServiceUpdateURL: %v", r.ServiceName, err)
+	}
+	return nil
+}
+
+// checkAbsoluteURL 检查raw是否是包含协议和主机的绝对URL
+func checkAbsoluteURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", raw)
+	}
+	return nil
+}
+
+// key 返回由服务名称和URL组成的实例标识，可用作映射的键
+func (r Registration) key() string {
+	return string(r.ServiceName) + " " + r.ServiceURL
+}
+
+// DefaultEndpoint 是默认端点的名称，对应Registration.ServiceURL
+const DefaultEndpoint = "default"
+
+// lookupEndpoint 在服务URL和命名端点集合中查找指定名称的端点
+// 名称为空或为DefaultEndpoint时返回serviceURL
+func lookupEndpoint(serviceURL string, endpoints map[string]string, name string) (string, bool) {
+	if name == "" || name == DefaultEndpoint {
+		return serviceURL, true
+	}
+	u, ok := endpoints[name]
+	return u, ok
 }
 
 // ServiceName 是服务名称的类型别名
@@ -46,6 +139,61 @@ type patchEntry struct {
 	Name ServiceName
 	// 服务URL
 	URL string
+	// 服务的命名端点，可选
+	Endpoints map[string]string `json:",omitempty"`
+	// 服务的标签，可选
+	Metadata map[string]string `json:",omitempty"`
+	// 服务的负载均衡权重，未设置时为DefaultWeight
+	Weight *int `json:",omitempty"`
+	// 服务被移除的原因，仅出现在Removed条目中
+	Reason RemovalReason `json:",omitempty"`
+	// 服务实例更新前的URL，仅出现在Updated条目中
+	PrevURL string `json:",omitempty"`
+}
+
+// RemovalReason 描述服务从注册中心移除的原因
+// 依赖方和运维人员可据此区分正常关闭、故障剔除和人工下线
+type RemovalReason string
+
+const (
+	// ReasonShutdown 表示服务正常关闭并主动注销
+	ReasonShutdown = RemovalReason("shutdown")
+
+	// ReasonEvicted 表示服务因失去响应被注册中心剔除
+	ReasonEvicted = RemovalReason("evicted")
+
+	// ReasonDrained 表示服务被运维人员主动下线
+	ReasonDrained = RemovalReason("drained")
+)
+
+// parseRemovalReason 解析移除原因，空字符串视为正常关闭
+func parseRemovalReason(s string) (RemovalReason, error) {
+	switch reason := RemovalReason(s); reason {
+	case "":
+		return ReasonShutdown, nil
+	case ReasonShutdown, ReasonEvicted, ReasonDrained:
+		return reason, nil
+	default:
+		return "", fmt.Errorf("unknown removal reason %q", s)
+	}
+}
+
+// validate 校验patchEntry是否可用
+// 服务名称不能为空，URL必须是包含协议和主机的绝对地址
+// 返回:
+// - error: 校验失败的原因
+func (pe patchEntry) validate() error {
+	if pe.Name == "" {
+		return fmt.Errorf("patch entry has empty service name (URL: %q)", pe.URL)
+	}
+	u, err := url.Parse(pe.URL)
+	if err != nil {
+		return fmt.Errorf("patch entry for %v has invalid URL %q: %v", pe.Name, pe.URL, err)
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("patch entry for %v has non-absolute URL %q", pe.Name, pe.URL)
+	}
+	return nil
 }
 
 // patch 结构用于服务依赖更新通知
@@ -56,4 +204,35 @@ type patch struct {
 
 	// Removed 包含被移除的依赖服务信息
 	Removed []patchEntry
+
+	// Updated 包含URL发生变化的依赖服务信息，PrevURL为原来的URL
+	Updated []patchEntry `json:",omitempty"`
+}
+
+// sanitize 返回只包含合法条目的patch副本
+// 非法条目会被跳过并记录日志，避免垃圾数据污染本地的服务提供者缓存
+func (p patch) sanitize() patch {
+	var clean patch
+	for _, entry := range p.Added {
+		if err := entry.validate(); err != nil {
+			log.Printf("skipping invalid added entry: %v", err)
+			continue
+		}
+		clean.Added = append(clean.Added, entry)
+	}
+	for _, entry := range p.Removed {
+		if err := entry.validate(); err != nil {
+			log.Printf("skipping invalid removed entry: %v", err)
+			continue
+		}
+		clean.Removed = append(clean.Removed, entry)
+	}
+	for _, entry := range p.Updated {
+		if err := entry.validate(); err != nil {
+			log.Printf("skipping invalid updated entry: %v", err)
+			continue
+		}
+		clean.Updated = append(clean.Updated, entry)
+	}
+	return clean
 }
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// validRegistration 返回一个能通过Validate的注册信息
+func validRegistration() Registration {
+	return Registration{
+		ServiceName:      LogService,
+		ServiceURL:       "http://localhost:4000",
+		ServiceUpdateURL: "http://localhost:4000/services",
+	}
+}
+
+func TestValidateAcceptsCompleteRegistration(t *testing.T) {
+	if err := validRegistration().Validate(); err != nil {
+		t.Errorf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestValidateRejectsEmptyName(t *testing.T) {
+	r := validRegistration()
+	r.ServiceName = ""
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "empty service name") {
+		t.Errorf("Validate() = %v, want an empty name error", err)
+	}
+}
+
+func TestValidateRejectsRelativeServiceURL(t *testing.T) {
+	r := validRegistration()
+	r.ServiceURL = "localhost:4000"
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "invalid ServiceURL") {
+		t.Errorf("Validate() = %v, want an invalid ServiceURL error", err)
+	}
+}
+
+func TestValidateRejectsEmptyServiceURL(t *testing.T) {
+	r := validRegistration()
+	r.ServiceURL = ""
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "invalid ServiceURL") {
+		t.Errorf("Validate() = %v, want an invalid ServiceURL error", err)
+	}
+}
+
+func TestValidateRejectsUnparseableUpdateURL(t *testing.T) {
+	r := validRegistration()
+	r.ServiceUpdateURL = "http://[::1"
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "invalid ServiceUpdateURL") {
+		t.Errorf("Validate() = %v, want an invalid ServiceUpdateURL error", err)
+	}
+}
+
+func TestValidateRejectsPathOnlyUpdateURL(t *testing.T) {
+	r := validRegistration()
+	r.ServiceUpdateURL = "/services"
+	if err := r.Validate(); err == nil || !strings.Contains(err.Error(), "invalid ServiceUpdateURL") {
+		t.Errorf("Validate() = %v, want an invalid ServiceUpdateURL error", err)
+	}
+}
+
+func TestRegistryRejectsInvalidRegistration(t *testing.T) {
+	withRegistrations(t, nil)
+	r := validRegistration()
+	r.ServiceURL = "not a url"
+	body, _ := json.Marshal(r)
+
+	w := httptest.NewRecorder()
+	RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/services", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("POST of an invalid registration = %d, want 400", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "invalid ServiceURL") {
+		t.Errorf("response body %q does not carry the validation message", w.Body.String())
+	}
+	if len(reg.registrations) != 0 {
+		t.Errorf("invalid registration was stored: %v", reg.registrations)
+	}
+}
@@ -2,22 +2,30 @@ package registry
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"reflect"
 	"sync"
+	"time"
 )
 
-// ServicesURL 是注册中心服务的完整URL
-// 所有服务注册和注销请求都发送到此URL
+// ServicesURL 是注册中心服务的默认完整URL
+// 未通过SetRegistryURL或REGISTRY_URL环境变量配置时，所有服务注册和注销请求都发送到此URL
 const ServicesURL = "http://localhost" + ServicePort + "/services"
 
 // ServicePort 是注册中心服务监听的端口
 // 微服务架构中，注册中心通常在固定端口提供服务
 const ServicePort = ":3000"
 
+// ErrServiceNotFound 表示注册表中没有要移除的服务
+// 服务可能已被心跳检查剔除，注销时遇到此错误可视为已经注销
+var ErrServiceNotFound = errors.New("service not found")
+
 // registry 结构体是整个服务注册中心的核心
 // 它存储和管理所有已注册的微服务信息，并处理服务依赖关系
 type registry struct {
@@ -28,6 +36,89 @@ type registry struct {
 	// mu 是读写互斥锁，保证对注册表的并发访问安全
 	// 因为多个服务可能同时注册或注销
 	mu *sync.RWMutex
+
+	// strict 为true时，注册前会探测服务的ServiceUpdateURL
+	// 无法送达依赖更新的服务会被拒绝注册，默认关闭(宽松模式)
+	strict bool
+
+	// ctx 是注册中心的生命周期上下文
+	// 注册中心关闭时ctx被取消，进行中的依赖推送随之中止
+	ctx context.Context
+
+	// notifier 负责把patch送达服务，默认通过HTTP发送
+	notifier Notifier
+
+	// clock 是注册中心获取时间的来源，测试中可替换为FakeClock
+	clock Clock
+
+	// sendAttempts 是推送patch的最大尝试次数，包含第一次发送
+	sendAttempts int
+
+	// sendBaseDelay 是推送失败后第一次重试前的等待时间，之后每次翻倍
+	sendBaseDelay time.Duration
+
+	// synchronousNotify 为true时，add和remove会等待向依赖方的推送全部完成后才返回
+	// 默认false，推送在后台并发进行
+	synchronousNotify bool
+
+	// pool 是执行依赖推送的工作池，限制同时进行的推送数量
+	pool *notifyPool
+
+	// snapshotMaxAge 是从状态文件恢复的条目允许的最大年龄，0表示不限制
+	snapshotMaxAge time.Duration
+}
+
+// 推送patch的默认重试策略: 共尝试3次，依次等待100ms、200ms
+const (
+	DefaultSendAttempts  = 3
+	DefaultSendBaseDelay = 100 * time.Millisecond
+)
+
+// SetSendRetry 设置推送patch失败时的重试策略
+// 依赖方短暂繁忙时，重试可以避免它永远错过这次依赖更新
+// 参数:
+// - attempts: 最大尝试次数(包含第一次发送)，小于1时按1处理
+// - baseDelay: 第一次重试前的等待时间，之后每次重试翻倍
+func SetSendRetry(attempts int, baseDelay time.Duration) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.sendAttempts = attempts
+	reg.sendBaseDelay = baseDelay
+}
+
+// SetContext 设置注册中心的生命周期上下文
+// ctx被取消后，尚未发出的依赖推送会被跳过，进行中的推送请求会被中止
+// 参数:
+// - ctx: 注册中心的生命周期上下文
+func SetContext(ctx context.Context) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.ctx = ctx
+}
+
+// SetSynchronousNotify 设置注册、注销是否等待依赖推送完成后才返回
+// 启用后，注册请求返回时所有依赖方都已收到(或确认未能收到)更新，
+// 适用于测试以及要求依赖方状态严格一致的场景
+// 参数:
+// - sync: 是否同步推送
+func SetSynchronousNotify(sync bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.synchronousNotify = sync
+}
+
+// SetStrictRegistration 设置注册中心是否启用严格注册模式
+// 严格模式下，ServiceUpdateURL不可达的服务会被拒绝注册，
+// 避免服务"注册成功"却永远收不到依赖更新
+// 参数:
+// - strict: 是否启用严格模式
+func SetStrictRegistration(strict bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.strict = strict
 }
 
 // add 方法向注册表中添加新的服务
@@ -38,39 +129,135 @@ type registry struct {
 // 返回:
 // - error: 添加过程中的错误
 func (r *registry) add(reg Registration) error {
+	// 严格模式下先确认服务能够接收依赖更新
+	r.mu.RLock()
+	strict := r.strict
+	r.mu.RUnlock()
+	if strict {
+		if err := r.probe(reg.ServiceUpdateURL); err != nil {
+			return fmt.Errorf("rejecting %v at %v: service update URL unreachable: %w",
+				reg.ServiceName, reg.ServiceURL, err)
+		}
+	}
+
 	// 加锁，确保并发安全，防止多个服务同时修改注册表
 	r.mu.Lock()
 
+	// 同一个URL不能被不同名称的服务占用，否则按URL注销时无法确定移除哪个服务
+	for _, existing := range r.registrations {
+		if existing.ServiceURL == reg.ServiceURL && existing.ServiceName != reg.ServiceName {
+			r.mu.Unlock()
+			return fmt.Errorf("service URL %v is already registered by %v, cannot register %v",
+				reg.ServiceURL, existing.ServiceName, reg.ServiceName)
+		}
+	}
+
+	// 带有InstanceID的服务重启后重新注册时，原地更新已有的注册信息
+	var prev *Registration
+	if reg.InstanceID != "" {
+		for i := range r.registrations {
+			if r.registrations[i].InstanceID == reg.InstanceID &&
+				r.registrations[i].ServiceName == reg.ServiceName {
+				old := r.registrations[i]
+				prev = &old
+				r.registrations[i] = reg
+				break
+			}
+		}
+	}
+
+	// 同一服务用相同URL重复注册(例如网络抖动后的重试)时原地更新，
+	// 保证注册是幂等的，负载均衡不会重复计算同一个实例
+	if prev == nil {
+		for i := range r.registrations {
+			if r.registrations[i].ServiceURL == reg.ServiceURL {
+				old := r.registrations[i]
+				prev = &old
+				r.registrations[i] = reg
+				break
+			}
+		}
+	}
+
 	// 添加新服务到注册表
-	r.registrations = append(r.registrations, reg)
+	if prev == nil {
+		r.registrations = append(r.registrations, reg)
+	}
+
+	// 在同一把写锁下确定两个方向的依赖关系:
+	// 新服务需要的已有服务，以及需要新服务的已有服务
+	// 并发注册的服务在这里串行化，后注册的一方负责完成双方的连接，
+	// 依赖方既不会漏掉更新，也不会重复收到同一个实例
+	required := r.requiredPatch(reg)
+	dependents := append(make([]Registration, 0, len(r.registrations)), r.registrations...)
 
 	// 操作完成后释放锁
 	r.mu.Unlock()
 
 	// 执行依赖推送机制
-	// 当服务注册并声明依赖时，查找并通知它依赖服务的信息
-	err := r.sendRequireServices(reg)
+	// 当服务注册并声明依赖时，通知它依赖服务的信息
+	err := r.sendPatch(required, reg.ServiceUpdateURL)
+
+	// 已知实例换了URL，或以相同URL重新注册但端点、标签、权重有变化时，
+	// 只向依赖方发送一条更新通知，而不是先移除再新增；注册信息没有变化时不通知
+	if prev != nil {
+		if !reflect.DeepEqual(prev.entry(), reg.entry()) {
+			updated := reg.entry()
+			updated.PrevURL = prev.ServiceURL
+			r.notifyRegistrations(dependents, patch{Updated: []patchEntry{updated}})
+		}
+		return err
+	}
+
 	// log服务通知需要log服务的服务
-	r.notify(patch{
-		Added: []patchEntry{
-			{
-				Name: reg.ServiceName,
-				URL:  reg.ServiceURL,
-			},
-		},
-	})
+	r.notifyRegistrations(dependents, patch{Added: []patchEntry{reg.entry()}})
 	return err
 }
 
-// log服务通知需要log服务的服务
-func (r registry) notify(fullPatch patch) {
+// notify 将fullPatch推送给所有依赖其中服务的已注册服务
+// 在读锁下复制注册列表后立即释放锁，所有HTTP推送都在副本上进行，
+// 避免慢速的依赖方长时间占用读锁阻塞add/remove，
+// 也避免依赖方回调注册中心时发生死锁
+// 参数:
+// - fullPatch: 完整的变更集合
+func (r *registry) notify(fullPatch patch) {
+	r.mu.RLock()
+	regs := make([]Registration, len(r.registrations))
+	copy(regs, r.registrations)
+	r.mu.RUnlock()
+
+	r.notifyRegistrations(regs, fullPatch)
+}
+
+// notifyRegistrations 向指定的服务推送fullPatch中与其依赖相关的部分
+// 调用方负责保证regs在推送期间不被修改
+// 参数:
+// - regs: 接收通知的服务
+// - fullPatch: 完整的变更集合
+func (r *registry) notifyRegistrations(regs []Registration, fullPatch patch) {
+	// 在读锁下读取配置，SetContext和SetSynchronousNotify可能同时在修改它们
 	r.mu.RLock()
-	defer r.mu.RUnlock()
+	ctx, synchronous := r.ctx, r.synchronousNotify
+	r.mu.RUnlock()
 
-	for _, reg := range r.registrations {
-		//使用协程并发处理每个服务  并发的发出通知
-		go func(reg Registration) {
+	// 同步推送模式下等待所有推送goroutine结束
+	var wg sync.WaitGroup
+	if synchronous {
+		defer wg.Wait()
+	}
+	for _, reg := range regs {
+		//注册中心正在关闭，剩余的通知不再发出
+		if ctx.Err() != nil {
+			return
+		}
+		//提交到工作池并发处理每个服务，并发数受工作池大小限制
+		wg.Add(1)
+		r.pool.submit(func() {
+			defer wg.Done()
 			for _, reqService := range reg.RequireServices {
+				if ctx.Err() != nil {
+					return
+				}
 				//创建一个patch对象，用于存储依赖更新信息
 				p := patch{Added: []patchEntry{}, Removed: []patchEntry{}}
 				sendUpdate := false
@@ -90,20 +277,81 @@ func (r registry) notify(fullPatch patch) {
 						sendUpdate = true
 					}
 				}
+				for _, updated := range fullPatch.Updated {
+					if updated.Name == reqService {
+						p.Updated = append(p.Updated, updated)
+						sendUpdate = true
+					}
+				}
 				//如果需要发送更新
 				if sendUpdate {
 					//发送更新请求
 					err := r.sendPatch(p, reg.ServiceUpdateURL)
 					if err != nil {
-						log.Println(err)
+						log.Printf("failed to notify %v at %v: %v",
+							reg.ServiceName, reg.ServiceUpdateURL, err)
 						return
 					}
 				}
 			}
-		}(reg)
+		})
 	}
 }
 
+// replaceAll 原子地替换整个注册表，用于蓝绿切换和受控迁移
+// 业务流程:
+// 1. 在写锁下计算新旧注册集合的差异并整体替换
+// 2. 向新旧集合中都存在的服务推送增量patch
+// 3. 向新加入的服务推送它们依赖的完整服务信息
+// 被移除的服务不再接收任何通知
+// 参数:
+// - regs: 新的完整注册集合
+func (r *registry) replaceAll(regs []Registration) {
+	r.mu.Lock()
+	oldSet := make(map[string]bool, len(r.registrations))
+	for _, old := range r.registrations {
+		oldSet[old.key()] = true
+	}
+	newSet := make(map[string]bool, len(regs))
+	for _, reg := range regs {
+		newSet[reg.key()] = true
+	}
+
+	var delta patch
+	var retained, joined []Registration
+	for _, reg := range regs {
+		if oldSet[reg.key()] {
+			retained = append(retained, reg)
+		} else {
+			joined = append(joined, reg)
+			delta.Added = append(delta.Added, reg.entry())
+		}
+	}
+	for _, old := range r.registrations {
+		if !newSet[old.key()] {
+			delta.Removed = append(delta.Removed, old.entry())
+		}
+	}
+	r.registrations = append(make([]Registration, 0, len(regs)), regs...)
+	r.mu.Unlock()
+
+	// retained和joined是独立的切片，推送期间不受注册表后续修改的影响
+	r.notifyRegistrations(retained, delta)
+	for _, reg := range joined {
+		if err := r.sendRequireServices(reg); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// ReplaceAll 原子地用regs替换注册中心的全部注册信息
+// 依赖方只会收到新旧集合之间的增量变化，使服务发现状态收敛到新集合
+// 参数:
+// - regs: 新的完整注册集合
+func ReplaceAll(regs []Registration) {
+	reg.replaceAll(regs)
+}
+
 // sendRequireServices 实现服务依赖发现和通知
 // 业务流程:
 // 1. 检查新注册服务声明的依赖
@@ -113,12 +361,24 @@ func (r registry) notify(fullPatch patch) {
 // - reg: 新注册的服务信息，包含其依赖需求
 // 返回:
 // - error: 处理过程中的错误
-func (r registry) sendRequireServices(reg Registration) error {
+func (r *registry) sendRequireServices(reg Registration) error {
 	// 使用读锁访问注册表，允许并发读取
 	r.mu.RLock()
-	defer r.mu.RUnlock()
+	p := r.requiredPatch(reg)
+	r.mu.RUnlock()
+
+	// 发送依赖更新通知
+	// 将找到的依赖服务信息发送到新服务的更新端点
+	return r.sendPatch(p, reg.ServiceUpdateURL)
+}
 
-	// 创建patch对象，用于存储依赖更新信息
+// requiredPatch 构造包含reg所依赖的全部已注册服务的patch
+// 调用方必须持有r.mu(读锁或写锁)
+// 参数:
+// - reg: 声明了依赖的服务
+// 返回:
+// - patch: Added中是所有满足reg依赖的服务实例
+func (r *registry) requiredPatch(reg Registration) patch {
 	var p patch
 
 	// 双重循环:
@@ -127,84 +387,156 @@ func (r registry) sendRequireServices(reg Registration) error {
 	// 目的是找到所有匹配的依赖服务
 	for _, serviceReg := range r.registrations {
 		for _, reqService := range reg.RequireServices {
-			// 当找到匹配的依赖服务时
+			// 当找到匹配的依赖服务时，添加到patch中
 			if serviceReg.ServiceName == reqService {
-				// 创建patchEntry并添加到patch中
-				p.Added = append(p.Added, patchEntry{
-					Name: serviceReg.ServiceName,
-					URL:  serviceReg.ServiceURL,
-				})
+				p.Added = append(p.Added, serviceReg.entry())
 			}
 		}
 	}
-
-	// 发送依赖更新通知
-	// 将找到的依赖服务信息发送到新服务的更新端点
-	err := r.sendPatch(p, reg.ServiceUpdateURL)
-	if err != nil {
-		return err
-	}
-	return nil
+	return p
 }
 
 // sendPatch 将依赖更新信息发送到指定服务
-// 通过HTTP POST请求将patch对象发送到服务的更新端点
+// 通过Notifier(默认为HTTP POST请求)将patch对象发送到服务的更新端点
+// 发送失败时按指数退避重试，最多尝试sendAttempts次；注册中心关闭时立即放弃
 // 参数:
 // - p: 包含依赖更新信息的patch对象
 // - url: 接收更新的服务端点URL
 // 返回:
 // - error: 发送过程中的错误
-func (r registry) sendPatch(p patch, url string) error {
+func (r *registry) sendPatch(p patch, url string) error {
 	// 将patch对象序列化为JSON
 	d, err := json.Marshal(p)
 	if err != nil {
 		return err
 	}
 
-	// 发送HTTP POST请求
-	// Content-Type为application/json
-	_, err = http.Post(url, "application/json", bytes.NewBuffer(d))
-	if err != nil {
-		return err
+	// 在读锁下读取推送配置，运行期间调用SetNotifier、SetSendRetry等不会产生数据竞争
+	r.mu.RLock()
+	ctx, notifier, clock := r.ctx, r.notifier, r.clock
+	attempts, delay := r.sendAttempts, r.sendBaseDelay
+	r.mu.RUnlock()
+
+	// 通过Notifier发送，默认为HTTP POST，Content-Type为application/json
+	for attempt := 1; ; attempt++ {
+		err = notifier.Notify(ctx, url, d)
+		if err == nil || attempt >= attempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-clock.After(delay):
+		}
+		delay *= 2
 	}
-	return nil
+}
+
+// probe 向服务的更新端点发送一个空patch，确认其可达且能正确处理更新
+// 参数:
+// - url: 服务的ServiceUpdateURL
+// 返回:
+// - error: 端点不可达或未能成功处理时返回错误
+func (r *registry) probe(url string) error {
+	return r.sendPatch(patch{}, url)
 }
 
 // remove 方法从注册表中移除服务
 // 当服务关闭或需要注销时调用此方法
 // 参数:
 // - url: 要移除的服务URL
+// - reason: 移除原因，写入审计日志并随Removed通知推送给依赖方
 // 返回:
 // - error: 移除过程中的错误或服务未找到错误
-func (r *registry) remove(url string) error {
+func (r *registry) remove(url string, reason RemovalReason) error {
+	// 加写锁，查找和移除在同一临界区内完成
+	r.mu.Lock()
+	var removed *Registration
 	// 查找匹配URL的服务
-	for i := range reg.registrations {
-		if reg.registrations[i].ServiceURL == url {
-			r.notify(patch{
-				Removed: []patchEntry{
-					{
-						Name: r.registrations[i].ServiceName,
-						URL:  r.registrations[i].ServiceURL,
-					},
-				},
-			})
-			// 加锁确保并发安全
-			r.mu.Lock()
+	for i := range r.registrations {
+		if r.registrations[i].ServiceURL == url {
+			target := r.registrations[i]
+			removed = &target
 			// 通过切片操作移除该服务
-			reg.registrations = append(reg.registrations[:i], r.registrations[:i+1]...)
-			r.mu.Unlock()
-			return nil
+			r.registrations = append(r.registrations[:i], r.registrations[i+1:]...)
+			break
 		}
 	}
+	r.mu.Unlock()
+
 	// 未找到匹配服务时返回错误
-	return fmt.Errorf("service at url %s not found", url)
+	if removed == nil {
+		return fmt.Errorf("%w: no service at url %s", ErrServiceNotFound, url)
+	}
+
+	// 审计日志，记录服务被移除的原因
+	log.Printf("audit: removed %v at %v (reason: %v)", removed.ServiceName, url, reason)
+
+	// 释放锁之后再通知依赖方，notify内部会获取读锁
+	r.notify(patch{
+		Removed: []patchEntry{
+			{
+				Name:   removed.ServiceName,
+				URL:    removed.ServiceURL,
+				Reason: reason,
+			},
+		},
+	})
+	return nil
+}
+
+// removeByName 从注册表中移除名为name的全部服务实例
+// 用于运维清理，例如一次下线某个服务的所有实例
+// 参数:
+// - name: 服务名称
+// - reason: 移除原因，写入审计日志并随Removed通知推送给依赖方
+// 返回:
+// - int: 移除的实例数量
+// - error: 没有该名称的服务时返回错误
+func (r *registry) removeByName(name ServiceName, reason RemovalReason) (int, error) {
+	// 加写锁，在同一临界区内筛选出保留和移除的服务
+	r.mu.Lock()
+	var removed []Registration
+	kept := r.registrations[:0]
+	for _, registration := range r.registrations {
+		if registration.ServiceName == name {
+			removed = append(removed, registration)
+		} else {
+			kept = append(kept, registration)
+		}
+	}
+	r.registrations = kept
+	r.mu.Unlock()
+
+	if len(removed) == 0 {
+		return 0, fmt.Errorf("%w: no services named %v registered", ErrServiceNotFound, name)
+	}
+
+	// 审计日志，并把所有实例的移除合并为一次通知
+	var p patch
+	for _, registration := range removed {
+		log.Printf("audit: removed %v at %v (reason: %v)",
+			registration.ServiceName, registration.ServiceURL, reason)
+		entry := registration.entry()
+		entry.Reason = reason
+		p.Removed = append(p.Removed, entry)
+	}
+	r.notify(p)
+	return len(removed), nil
 }
 
 // 初始化全局注册表实例
 // 这是注册中心的单例对象，存储所有服务信息
 var reg = registry{
-	registrations: make([]Registration, 0),
-	mu:            new(sync.RWMutex),
+	registrations:  make([]Registration, 0),
+	mu:             new(sync.RWMutex),
+	ctx:            context.Background(),
+	notifier:       httpNotifier{},
+	clock:          realClock{},
+	sendAttempts:   DefaultSendAttempts,
+	sendBaseDelay:  DefaultSendBaseDelay,
+	pool:           newNotifyPool(defaultNotifyWorkers),
+	snapshotMaxAge: DefaultSnapshotMaxAge,
 }
 
 // RegistryService 实现了http.Handler接口
@@ -214,7 +546,7 @@ type RegistryService struct{}
 
 // ServeHTTP 实现http.Handler接口，处理HTTP请求
 // 业务流程:
-// 1. 接收服务注册(POST)或注销(DELETE)请求
+// 1. 接收服务注册(POST)、注销(DELETE)或查询(GET)请求
 // 2. 解析请求内容
 // 3. 更新注册表
 // 4. 处理依赖关系
@@ -225,6 +557,13 @@ func (s RegistryService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// 记录收到的请求
 	log.Println("Request received")
 
+	// 配置了共享密钥时，拒绝未携带或携带错误密钥的请求
+	if !authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="registry"`)
+		http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
+
 	// 根据HTTP方法处理不同类型的请求
 	switch r.Method {
 	case http.MethodPost: // 处理服务注册请求
@@ -239,6 +578,13 @@ func (s RegistryService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// 校验注册信息，拒绝会破坏依赖推送的无效条目
+		if err := r.Validate(); err != nil {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// 记录服务注册信息
 		log.Printf("adding service: %v with URL: %v", r.ServiceName, r.ServiceURL)
 
@@ -251,9 +597,24 @@ func (s RegistryService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		writeStatus(w, "registered")
+
+	case http.MethodGet: // 列出所有已注册的服务
+		// 在读锁下只复制注册表，没有服务时返回空数组而不是null
+		reg.mu.RLock()
+		registrations := append(make([]Registration, 0, len(reg.registrations)),
+			reg.registrations...)
+		reg.mu.RUnlock()
+
+		// 释放锁之后直接编码到响应中，注册表很大时也不在内存中拼出完整的响应体
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(registrations); err != nil {
+			log.Println(err)
+		}
 
 	case http.MethodDelete: // 处理服务注销请求
-		// 读取请求体中的服务URL
+		// 读取请求体: {"url":"http://..."}或{"name":"LogService"}形式的JSON，
+		// 或旧版客户端发送的纯文本服务URL
 		payload, err := io.ReadAll(r.Body)
 		if err != nil {
 			log.Println(err)
@@ -261,17 +622,56 @@ func (s RegistryService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// 提取服务URL并记录
-		url := string(payload)
-		log.Printf("Removing service at URL: %s", url)
+		// 解析移除原因
+		reason, err := parseRemovalReason(r.URL.Query().Get("reason"))
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// 根据首字节区分JSON和纯文本，首尾空白不影响解析
+		trimmed := bytes.TrimSpace(payload)
+		url := string(trimmed)
+		if len(trimmed) > 0 && trimmed[0] == '{' {
+			var body deregistration
+			if err := json.Unmarshal(trimmed, &body); err != nil || (body.URL == "") == (body.Name == "") {
+				http.Error(w, `request body must be {"url": ...}, {"name": ...} or a service URL`,
+					http.StatusBadRequest)
+				return
+			}
+
+			// 按服务名称移除该服务的全部实例
+			if body.Name != "" {
+				log.Printf("Removing all instances of %v (reason: %v)", body.Name, reason)
+				if _, err := reg.removeByName(body.Name, reason); err != nil {
+					log.Println(err)
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				writeStatus(w, "deregistered")
+				return
+			}
+			url = body.URL
+		}
+
+		// 记录要移除的服务URL
+		log.Printf("Removing service at URL: %s (reason: %v)", url, reason)
 
 		// 从注册表中移除服务
-		err = reg.remove(url)
+		// 服务不存在时返回404，客户端据此判断服务已经注销
+		err = reg.remove(url, reason)
+		if errors.Is(err, ErrServiceNotFound) {
+			log.Println(err)
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			log.Println(err)
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		writeStatus(w, "deregistered")
 
 	default: // 不支持的HTTP方法
 		// 返回405 Method Not Allowed错误
@@ -279,3 +679,23 @@ func (s RegistryService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// deregistration 是DELETE /services的JSON请求体，URL和Name必须且只能设置一个
+type deregistration struct {
+	// URL 是要注销的服务实例URL
+	URL string `json:"url,omitempty"`
+
+	// Name 是要注销全部实例的服务名称
+	Name ServiceName `json:"name,omitempty"`
+}
+
+// writeStatus 显式返回200状态码和形如{"status":"registered"}的JSON响应体
+// 客户端依据状态码判断操作是否成功，因此不依赖net/http隐式写入的200
+// 参数:
+// - w: 响应写入器
+// - status: 操作结果描述
+func writeStatus(w http.ResponseWriter, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
@@ -0,0 +1,735 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withRegistrations 在测试期间替换全局注册表的内容，测试结束后恢复
+func withRegistrations(t *testing.T, regs []Registration) {
+	t.Helper()
+	reg.mu.Lock()
+	saved := reg.registrations
+	reg.registrations = regs
+	reg.mu.Unlock()
+	t.Cleanup(func() {
+		reg.mu.Lock()
+		reg.registrations = saved
+		reg.mu.Unlock()
+	})
+}
+
+func TestGetServicesStreamsAllRegistrations(t *testing.T) {
+	const n = 5000
+	regs := make([]Registration, n)
+	for i := range regs {
+		url := fmt.Sprintf("http://localhost:%d", 10000+i)
+		regs[i] = Registration{ServiceName: LogService, ServiceURL: url, ServiceUpdateURL: url + "/services"}
+	}
+	withRegistrations(t, regs)
+
+	w := httptest.NewRecorder()
+	RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/services", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var got []Registration
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("response is not a valid JSON array: %v", err)
+	}
+	if len(got) != n {
+		t.Fatalf("got %d registrations, want %d", len(got), n)
+	}
+	for i := range got {
+		if got[i].ServiceURL != regs[i].ServiceURL {
+			t.Fatalf("registration %d = %v, want %v", i, got[i].ServiceURL, regs[i].ServiceURL)
+		}
+	}
+}
+
+func TestGetServicesEmptyRegistryIsEmptyArray(t *testing.T) {
+	withRegistrations(t, []Registration{})
+
+	w := httptest.NewRecorder()
+	RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/services", nil))
+
+	if body := w.Body.String(); body != "[]\n" {
+		t.Fatalf("body = %q, want %q", body, "[]\n")
+	}
+}
+
+func TestGetServicesListsRegisteredServices(t *testing.T) {
+	withRegistrations(t, nil)
+	sink := newPatchRecorder(t)
+	for _, r := range []Registration{
+		{ServiceName: LogService, ServiceURL: "http://log", ServiceUpdateURL: sink.URL},
+		{ServiceName: GradingService, ServiceURL: "http://grading", ServiceUpdateURL: sink.URL},
+	} {
+		body, _ := json.Marshal(r)
+		w := httptest.NewRecorder()
+		RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/services", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("registering %v = %d, want 200", r.ServiceName, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/services", nil))
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var got []Registration
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ServiceName != LogService || got[1].ServiceName != GradingService {
+		t.Fatalf("GET /services = %v, want the two registered services", got)
+	}
+}
+
+func TestCancelStopsFanoutMidway(t *testing.T) {
+	var calls atomic.Int64
+	started := make(chan struct{})
+	r := newTestRegistry(notifierFunc(func(ctx context.Context, _ string, _ []byte) error {
+		// 第一次推送一直阻塞，直到注册中心关闭
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}), 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.ctx = ctx
+	r.synchronousNotify = true
+
+	done := make(chan struct{})
+	go func() {
+		r.notifyRegistrations(dependents(50), patch{Added: []patchEntry{{Name: LogService, URL: "http://log"}}})
+		close(done)
+	}()
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fanout did not stop after the registry context was cancelled")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("%d sends attempted, want only the one in flight at cancellation", n)
+	}
+}
+
+func TestSynchronousNotifyDeliversBeforeAddReturns(t *testing.T) {
+	withRegistrations(t, nil)
+	var mu sync.Mutex
+	delivered := make(map[string]int)
+	// 推送故意放慢，异步模式下add返回时依赖方还没有收到更新
+	SetNotifier(notifierFunc(func(_ context.Context, url string, payload []byte) error {
+		time.Sleep(20 * time.Millisecond)
+		var p patch
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[url] += len(p.Added)
+		return nil
+	}))
+	SetSynchronousNotify(true)
+	t.Cleanup(func() {
+		SetNotifier(nil)
+		SetSynchronousNotify(false)
+	})
+
+	if err := reg.add(dependents(3)[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.add(Registration{ServiceName: LogService, ServiceURL: "http://log",
+		ServiceUpdateURL: "http://log/services"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := delivered["http://dependent-0/services"]; got != 1 {
+		t.Fatalf("dependent had received %d Added entries when add returned, want 1", got)
+	}
+}
+
+func TestSendPatchRetriesUntilDelivered(t *testing.T) {
+	var calls atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 前两次请求失败，第三次成功
+		if calls.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	r := newTestRegistry(httpNotifier{}, 1)
+	r.sendAttempts = 3
+	r.sendBaseDelay = time.Millisecond
+	if err := r.sendPatch(patch{Added: []patchEntry{{Name: LogService, URL: "http://log"}}}, srv.URL); err != nil {
+		t.Fatalf("sendPatch gave up: %v", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("update endpoint called %d times, want 3", n)
+	}
+}
+
+func TestSendPatchConcurrentWithReconfiguration(t *testing.T) {
+	SetNotifier(notifierFunc(func(context.Context, string, []byte) error { return nil }))
+	t.Cleanup(func() {
+		SetNotifier(nil)
+		SetSendRetry(DefaultSendAttempts, DefaultSendBaseDelay)
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			SetSendRetry(i%3+1, time.Millisecond)
+			SetNotifier(notifierFunc(func(context.Context, string, []byte) error { return nil }))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := reg.sendPatch(patch{}, "http://dependent/services"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestReRegisteringSameURLIsIdempotent(t *testing.T) {
+	var mu sync.Mutex
+	var received []patch
+	r := newTestRegistry(notifierFunc(func(_ context.Context, url string, payload []byte) error {
+		if url != "http://dependent-0/services" {
+			return nil
+		}
+		var p patch
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, p)
+		return nil
+	}), 1)
+	r.synchronousNotify = true
+
+	logReg := Registration{ServiceName: LogService, ServiceURL: "http://log",
+		ServiceUpdateURL: "http://log/services", Metadata: map[string]string{"version": "v1"}}
+	for _, reg := range []Registration{dependents(1)[0], logReg, logReg} {
+		if err := r.add(reg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(r.registrations); n != 2 {
+		t.Fatalf("len(registrations) = %d after registering the same URL twice, want 2", n)
+	}
+	// 注册信息没有变化，依赖方只收到最初的Added
+	if len(received) != 2 || len(received[1].Added) != 1 {
+		t.Fatalf("dependent received %+v, want its initial patch and one Added", received)
+	}
+
+	// 以相同URL重新注册但标签变化时，依赖方收到一条Updated
+	logReg.Metadata = map[string]string{"version": "v2"}
+	if err := r.add(logReg); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.registrations); n != 2 {
+		t.Fatalf("len(registrations) = %d after upsert, want 2", n)
+	}
+	if len(received) != 3 || len(received[2].Updated) != 1 ||
+		received[2].Updated[0].Metadata["version"] != "v2" || received[2].Updated[0].PrevURL != "http://log" {
+		t.Fatalf("dependent received %+v, want an Updated carrying version v2", received)
+	}
+}
+
+func TestReplaceAllSendsOnlyTheDelta(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 4)
+	r.synchronousNotify = true
+
+	logSink := newPatchRecorder(t)
+	logService := func(url string) Registration {
+		return Registration{ServiceName: LogService, ServiceURL: url, ServiceUpdateURL: logSink.URL}
+	}
+	dependent := newPatchRecorder(t)
+	grading := Registration{
+		ServiceName:      GradingService,
+		ServiceURL:       "http://grading",
+		ServiceUpdateURL: dependent.URL,
+		RequireServices:  []ServiceName{LogService},
+	}
+	joiner := newPatchRecorder(t)
+	portal := Registration{
+		ServiceName:      PortalService,
+		ServiceURL:       "http://portal",
+		ServiceUpdateURL: joiner.URL,
+		RequireServices:  []ServiceName{LogService},
+	}
+	r.registrations = []Registration{logService("http://log-a"), logService("http://log-b"), grading}
+
+	r.replaceAll([]Registration{logService("http://log-a"), logService("http://log-c"), grading, portal})
+
+	got := dependent.Patches()
+	if len(got) != 1 {
+		t.Fatalf("retained dependent received %d patches, want exactly 1", len(got))
+	}
+	if events := fmt.Sprint(describe(got[0].Patch)); events != "[+http://log-c -http://log-b]" {
+		t.Errorf("retained dependent received %v, want [+http://log-c -http://log-b]", events)
+	}
+
+	// 新加入的服务收到新集合中它依赖的全部服务
+	got = joiner.Patches()
+	if len(got) != 1 {
+		t.Fatalf("joining service received %d patches, want exactly 1", len(got))
+	}
+	if events := fmt.Sprint(describe(got[0].Patch)); events != "[+http://log-a +http://log-c]" {
+		t.Errorf("joining service received %v, want [+http://log-a +http://log-c]", events)
+	}
+	if n := len(r.registrations); n != 4 {
+		t.Errorf("registry holds %d registrations after ReplaceAll, want 4", n)
+	}
+}
+
+// unreachableURL 返回一个已经关闭的服务器地址，向它发送的请求必然失败
+func unreachableURL(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestStrictRegistrationAcceptsReachableUpdateURL(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 2)
+	r.strict = true
+	rec := newPatchRecorder(t)
+
+	if err := r.add(Registration{ServiceName: LogService, ServiceURL: "http://log", ServiceUpdateURL: rec.URL}); err != nil {
+		t.Fatalf("strict add with a reachable update URL failed: %v", err)
+	}
+	if len(r.registrations) != 1 {
+		t.Errorf("registry holds %d registrations, want 1", len(r.registrations))
+	}
+}
+
+func TestStrictRegistrationRejectsUnreachableUpdateURL(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 2)
+	r.strict = true
+
+	err := r.add(Registration{ServiceName: LogService, ServiceURL: "http://log", ServiceUpdateURL: unreachableURL(t)})
+	if err == nil || !strings.Contains(err.Error(), "service update URL unreachable") {
+		t.Fatalf("strict add with an unreachable update URL returned %v, want a rejection", err)
+	}
+	if len(r.registrations) != 0 {
+		t.Errorf("rejected service was registered: %v", r.registrations)
+	}
+}
+
+func TestLenientRegistrationKeepsUnreachableService(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 2)
+
+	// 宽松模式(默认)下推送失败只作为错误返回，服务仍然完成注册
+	r.add(Registration{ServiceName: LogService, ServiceURL: "http://log", ServiceUpdateURL: unreachableURL(t)})
+	if len(r.registrations) != 1 {
+		t.Errorf("registry holds %d registrations in lenient mode, want 1", len(r.registrations))
+	}
+}
+
+// captureLog 在测试期间把标准库日志输出写入返回的缓冲区
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	saved := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() { log.SetOutput(saved) })
+	return buf
+}
+
+// deregister 通过RegistryService发送DELETE请求注销url，query附加在/services之后
+func deregister(t *testing.T, query, url string) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/services"+query, strings.NewReader(url))
+	RegistryService{}.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// removalReasons 返回依赖方收到的所有Removed条目的移除原因
+func removalReasons(rec *patchRecorder) []RemovalReason {
+	var reasons []RemovalReason
+	for _, p := range rec.Patches() {
+		for _, e := range p.Patch.Removed {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	return reasons
+}
+
+func TestDeregisterRecordsRemovalReason(t *testing.T) {
+	dependent := newPatchRecorder(t)
+	logSink := newPatchRecorder(t)
+	withRegistrations(t, []Registration{
+		{ServiceName: LogService, ServiceURL: "http://log-1", ServiceUpdateURL: logSink.URL},
+		{ServiceName: LogService, ServiceURL: "http://log-2", ServiceUpdateURL: logSink.URL},
+		{ServiceName: GradingService, ServiceURL: "http://grading", ServiceUpdateURL: dependent.URL,
+			RequireServices: []ServiceName{LogService}},
+	})
+	SetSynchronousNotify(true)
+	t.Cleanup(func() { SetSynchronousNotify(false) })
+	audit := captureLog(t)
+
+	if code := deregister(t, "?reason=drained", "http://log-1"); code != http.StatusOK {
+		t.Fatalf("drain returned %v, want %v", code, http.StatusOK)
+	}
+	// 未指定原因的注销视为正常关闭
+	if code := deregister(t, "", "http://log-2"); code != http.StatusOK {
+		t.Fatalf("deregister returned %v, want %v", code, http.StatusOK)
+	}
+
+	if got := fmt.Sprint(removalReasons(dependent)); got != "[drained shutdown]" {
+		t.Errorf("dependent received removal reasons %v, want [drained shutdown]", got)
+	}
+	for _, line := range []string{
+		"audit: removed LogService at http://log-1 (reason: drained)",
+		"audit: removed LogService at http://log-2 (reason: shutdown)",
+	} {
+		if !strings.Contains(audit.String(), line) {
+			t.Errorf("audit log %q is missing %q", audit.String(), line)
+		}
+	}
+}
+
+func TestDeregisterRejectsUnknownReason(t *testing.T) {
+	withRegistrations(t, []Registration{{ServiceName: LogService, ServiceURL: "http://log"}})
+
+	if code := deregister(t, "?reason=bored", "http://log"); code != http.StatusBadRequest {
+		t.Errorf("deregister with an unknown reason returned %v, want %v", code, http.StatusBadRequest)
+	}
+	if len(reg.registrations) != 1 {
+		t.Error("service was removed despite the invalid reason")
+	}
+}
+
+func TestAddRejectsURLClaimedByAnotherName(t *testing.T) {
+	r := newTestRegistry(notifierFunc(func(context.Context, string, []byte) error { return nil }), 1)
+	if err := r.add(Registration{ServiceName: LogService, ServiceURL: "http://shared"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := r.add(Registration{ServiceName: GradingService, ServiceURL: "http://shared"})
+	if err == nil || !strings.Contains(err.Error(), "already registered by LogService") {
+		t.Fatalf("conflicting add returned %v, want a URL conflict error", err)
+	}
+	if len(r.registrations) != 1 || r.registrations[0].ServiceName != LogService {
+		t.Errorf("registrations after conflict = %v, want only the original LogService", r.registrations)
+	}
+}
+
+func TestRestartedInstanceSendsSingleUpdate(t *testing.T) {
+	r := newTestRegistry(httpNotifier{}, 2)
+	r.synchronousNotify = true
+	logSink := newPatchRecorder(t)
+	dependent := newPatchRecorder(t)
+	logInstance := func(url string) Registration {
+		return Registration{ServiceName: LogService, ServiceURL: url, ServiceUpdateURL: logSink.URL, InstanceID: "log-1"}
+	}
+
+	if err := r.add(logInstance("http://localhost:4001")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.add(Registration{ServiceName: GradingService, ServiceURL: "http://grading",
+		ServiceUpdateURL: dependent.URL, RequireServices: []ServiceName{LogService}}); err != nil {
+		t.Fatal(err)
+	}
+	// 重启后同一实例换了端口重新注册
+	if err := r.add(logInstance("http://localhost:4002")); err != nil {
+		t.Fatal(err)
+	}
+
+	got := dependent.Patches()
+	if len(got) != 2 {
+		t.Fatalf("dependent received %d patches, want the initial one and a single update", len(got))
+	}
+	if events := fmt.Sprint(describe(got[1].Patch)); events != "[~http://localhost:4001>http://localhost:4002]" {
+		t.Errorf("restart produced %v, want a single update from :4001 to :4002", events)
+	}
+	if len(r.registrations) != 2 || r.registrations[0].ServiceURL != "http://localhost:4002" {
+		t.Errorf("registrations = %v, want the instance updated in place", r.registrations)
+	}
+}
+
+func TestRemoveMiddleRegistrationKeepsOthers(t *testing.T) {
+	r := newTestRegistry(notifierFunc(func(context.Context, string, []byte) error { return nil }), 1)
+	r.registrations = []Registration{
+		{ServiceName: LogService, ServiceURL: "http://log"},
+		{ServiceName: GradingService, ServiceURL: "http://grading"},
+		{ServiceName: PortalService, ServiceURL: "http://portal"},
+	}
+
+	if err := r.remove("http://grading", ReasonShutdown); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.registrations) != 2 {
+		t.Fatalf("registry holds %d registrations after removing one of three, want 2", len(r.registrations))
+	}
+	if r.registrations[0].ServiceURL != "http://log" || r.registrations[1].ServiceURL != "http://portal" {
+		t.Errorf("remaining registrations = %v, want log and portal intact", r.registrations)
+	}
+	if err := r.remove("http://grading", ReasonShutdown); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("removing it again returned %v, want ErrServiceNotFound", err)
+	}
+}
+
+func TestRegisterAndDeregisterReportStatus(t *testing.T) {
+	withRegistrations(t, nil)
+	sink := newPatchRecorder(t)
+	body, _ := json.Marshal(Registration{ServiceName: LogService, ServiceURL: "http://log", ServiceUpdateURL: sink.URL})
+
+	w := httptest.NewRecorder()
+	RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/services", bytes.NewReader(body)))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"registered\"}\n" {
+		t.Errorf("registration = %d %q, want 200 {\"status\":\"registered\"}", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("registration Content-Type = %q, want application/json", ct)
+	}
+
+	w = httptest.NewRecorder()
+	RegistryService{}.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/services", strings.NewReader(`{"url":"http://log"}`)))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"deregistered\"}\n" {
+		t.Errorf("deregistration = %d %q, want 200 {\"status\":\"deregistered\"}", w.Code, w.Body.String())
+	}
+}
+
+// addedCounter 记录每个更新URL收到的Added条目，按服务URL计数
+type addedCounter struct {
+	mu    sync.Mutex
+	added map[string]map[string]int
+}
+
+func newAddedCounter() *addedCounter {
+	return &addedCounter{added: make(map[string]map[string]int)}
+}
+
+func (c *addedCounter) Notify(_ context.Context, url string, payload []byte) error {
+	var p patch
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range p.Added {
+		if c.added[url] == nil {
+			c.added[url] = make(map[string]int)
+		}
+		c.added[url][e.URL]++
+	}
+	return nil
+}
+
+// count 返回updateURL收到serviceURL的次数
+func (c *addedCounter) count(updateURL, serviceURL string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.added[updateURL][serviceURL]
+}
+
+var (
+	wiringLog = Registration{ServiceName: LogService, ServiceURL: "http://log",
+		ServiceUpdateURL: "http://log/services"}
+	wiringGrading = dependents(1)[0]
+)
+
+func TestDependentRegisteredBeforeDependencyIsWired(t *testing.T) {
+	c := newAddedCounter()
+	r := newTestRegistry(c, 2)
+	r.synchronousNotify = true
+
+	if err := r.add(wiringGrading); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.add(wiringLog); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := c.count(wiringGrading.ServiceUpdateURL, wiringLog.ServiceURL); n != 1 {
+		t.Errorf("dependent learned about the log service %d times, want 1", n)
+	}
+}
+
+func TestDependencyRegisteredBeforeDependentIsWired(t *testing.T) {
+	c := newAddedCounter()
+	r := newTestRegistry(c, 2)
+	r.synchronousNotify = true
+
+	if err := r.add(wiringLog); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.add(wiringGrading); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := c.count(wiringGrading.ServiceUpdateURL, wiringLog.ServiceURL); n != 1 {
+		t.Errorf("dependent learned about the log service %d times, want 1", n)
+	}
+}
+
+func TestConcurrentRegistrationWiresExactlyOnce(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		c := newAddedCounter()
+		r := newTestRegistry(c, 2)
+		r.synchronousNotify = true
+
+		var wg sync.WaitGroup
+		for _, reg := range []Registration{wiringGrading, wiringLog} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := r.add(reg); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if n := c.count(wiringGrading.ServiceUpdateURL, wiringLog.ServiceURL); n != 1 {
+			t.Fatalf("round %d: dependent learned about the log service %d times, want 1", i, n)
+		}
+	}
+}
+
+func TestDeregisterByNameRemovesAllInstances(t *testing.T) {
+	dependent := newPatchRecorder(t)
+	withRegistrations(t, []Registration{
+		{ServiceName: LogService, ServiceURL: "http://log-1", ServiceUpdateURL: "http://log-1/services"},
+		{ServiceName: GradingService, ServiceURL: "http://grading", ServiceUpdateURL: dependent.URL,
+			RequireServices: []ServiceName{LogService}},
+		{ServiceName: LogService, ServiceURL: "http://log-2", ServiceUpdateURL: "http://log-2/services"},
+		{ServiceName: LogService, ServiceURL: "http://log-3", ServiceUpdateURL: "http://log-3/services"},
+	})
+	SetSynchronousNotify(true)
+	t.Cleanup(func() { SetSynchronousNotify(false) })
+	captureLog(t)
+
+	if code := deregister(t, "", `{"name":"LogService"}`); code != http.StatusOK {
+		t.Fatalf("deregister by name returned %v, want %v", code, http.StatusOK)
+	}
+
+	reg.mu.RLock()
+	left := append([]Registration(nil), reg.registrations...)
+	reg.mu.RUnlock()
+	if len(left) != 1 || left[0].ServiceURL != "http://grading" {
+		t.Errorf("registrations left = %+v, want only the grading service", left)
+	}
+
+	patches := dependent.Patches()
+	if len(patches) != 1 {
+		t.Fatalf("dependent received %d patches, want all removals in one", len(patches))
+	}
+	var urls []string
+	for _, e := range patches[0].Patch.Removed {
+		urls = append(urls, e.URL)
+	}
+	if got := strings.Join(urls, " "); got != "http://log-1 http://log-2 http://log-3" {
+		t.Errorf("dependent was told to remove %v, want every log instance", got)
+	}
+}
+
+func TestDeregisterByUnknownNameIsNotFound(t *testing.T) {
+	withRegistrations(t, []Registration{{ServiceName: LogService, ServiceURL: "http://log"}})
+	captureLog(t)
+
+	if code := deregister(t, "", `{"name":"GradingService"}`); code != http.StatusNotFound {
+		t.Errorf("deregister unknown name returned %v, want %v", code, http.StatusNotFound)
+	}
+}
+
+func TestDeregisterRejectsBothURLAndName(t *testing.T) {
+	withRegistrations(t, []Registration{{ServiceName: LogService, ServiceURL: "http://log"}})
+	captureLog(t)
+
+	if code := deregister(t, "", `{"url":"http://log","name":"LogService"}`); code != http.StatusBadRequest {
+		t.Errorf("deregister with url and name returned %v, want %v", code, http.StatusBadRequest)
+	}
+	if code := deregister(t, "", `{"url":"http://log"}`); code != http.StatusOK {
+		t.Errorf("deregister with JSON url returned %v, want %v", code, http.StatusOK)
+	}
+}
+
+func TestSlowDependentDoesNotBlockConcurrentAdd(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	r := newTestRegistry(notifierFunc(func(_ context.Context, url string, _ []byte) error {
+		if url == "http://grading/services" {
+			entered <- struct{}{}
+			<-release
+		}
+		return nil
+	}), 2)
+	r.synchronousNotify = true
+	r.registrations = []Registration{{ServiceName: GradingService, ServiceURL: "http://grading",
+		ServiceUpdateURL: "http://grading/services", RequireServices: []ServiceName{LogService}}}
+
+	// 同步推送模式下notify一直等到依赖方返回
+	go r.notify(added(LogService, "http://log", nil))
+	<-entered
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.add(Registration{ServiceName: PortalService, ServiceURL: "http://portal",
+			ServiceUpdateURL: "http://portal/services"})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("add blocked while a slow dependent was being notified")
+	}
+}
+
+func TestDeregisterAcceptsJSONAndPlainBodies(t *testing.T) {
+	withRegistrations(t, []Registration{
+		{ServiceName: LogService, ServiceURL: "http://log-1"},
+		{ServiceName: LogService, ServiceURL: "http://log-2"},
+	})
+	captureLog(t)
+
+	if code := deregister(t, "", ` {"url":"http://log-1"} `); code != http.StatusOK {
+		t.Errorf("JSON body returned %v, want %v", code, http.StatusOK)
+	}
+	// 旧版客户端发送纯文本URL，首尾空白不影响解析
+	if code := deregister(t, "", "  http://log-2\n"); code != http.StatusOK {
+		t.Errorf("plain body returned %v, want %v", code, http.StatusOK)
+	}
+	reg.mu.RLock()
+	left := len(reg.registrations)
+	reg.mu.RUnlock()
+	if left != 0 {
+		t.Errorf("%d registrations left, want both removed", left)
+	}
+
+	if code := deregister(t, "", `{"url":`); code != http.StatusBadRequest {
+		t.Errorf("malformed JSON body returned %v, want %v", code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// RegistryURLEnv 是配置注册中心地址的环境变量，值的格式与ServicesURL相同
+const RegistryURLEnv = "REGISTRY_URL"
+
+// registryURL 是客户端实际使用的注册中心地址
+// 启动时从RegistryURLEnv读取，未设置时为ServicesURL
+var (
+	registryURL   = defaultRegistryURL()
+	registryURLMu sync.RWMutex
+)
+
+// defaultRegistryURL 返回环境变量中配置的注册中心地址，未配置时返回ServicesURL
+func defaultRegistryURL() string {
+	if u := os.Getenv(RegistryURLEnv); u != "" {
+		return u
+	}
+	return ServicesURL
+}
+
+// SetRegistryURL 设置注册中心的地址，注册、注销和预检请求都会发送到此地址
+// 参数:
+// - url: 注册中心/services端点的完整URL，例如http://10.0.0.5:3000/services，为空时恢复默认地址
+func SetRegistryURL(url string) {
+	if url == "" {
+		url = defaultRegistryURL()
+	}
+	registryURLMu.Lock()
+	defer registryURLMu.Unlock()
+	registryURL = url
+}
+
+// servicesURL 返回当前配置的注册中心地址
+func servicesURL() string {
+	registryURLMu.RLock()
+	defer registryURLMu.RUnlock()
+	return registryURL
+}
+
+// DefaultHTTPTimeout 是注册中心及其客户端出站请求的默认超时时间
+const DefaultHTTPTimeout = 5 * time.Second
+
+// httpClient 是注册中心及其客户端发送所有出站请求所使用的HTTP客户端
+// 设置超时可以避免一个挂起的服务让推送goroutine永远阻塞
+var (
+	httpClient   = &http.Client{Timeout: DefaultHTTPTimeout}
+	httpClientMu sync.RWMutex
+)
+
+// SetHTTPClient 替换出站请求所使用的HTTP客户端
+// 作用于注册、注销、依赖推送、心跳检查，以及日志客户端和成绩服务客户端
+// 参数:
+// - c: 新的HTTP客户端，传入nil时恢复默认客户端
+func SetHTTPClient(c *http.Client) {
+	if c == nil {
+		c = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	httpClient = c
+}
+
+// HTTPClient 返回当前配置的HTTP客户端
+// 日志客户端和成绩服务客户端也使用它，一次SetHTTPClient即可配置所有服务间调用
+func HTTPClient() *http.Client {
+	httpClientMu.RLock()
+	defer httpClientMu.RUnlock()
+	return httpClient
+}
+
+// do 使用当前配置的HTTP客户端发送请求
+func do(req *http.Request) (*http.Response, error) {
+	return HTTPClient().Do(req)
+}
+
+// NewTLSClient 创建一个信任caFile中CA证书的HTTP客户端，超时时间为DefaultHTTPTimeout
+// 服务使用自签名或内部CA签发的证书时，将返回值传给SetHTTPClient，
+// 注册、依赖推送、心跳检查、日志发送和成绩服务调用都会校验对方证书
+// 参数:
+// - caFile: PEM格式的CA证书文件，系统根证书仍然被信任
+// 返回:
+// - *http.Client: 配置好的HTTP客户端
+// - error: 读取或解析CA证书失败的原因
+func NewTLSClient(caFile string) (*http.Client, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no CA certificates found in %v", caFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Timeout: DefaultHTTPTimeout, Transport: transport}, nil
+}
+
+// outboundHeaders 是附加到注册中心及其客户端所有出站请求上的静态请求头
+// 例如链路追踪的baggage或内部调用的认证信息
+var (
+	outboundHeaders   http.Header
+	outboundHeadersMu sync.RWMutex
+)
+
+// SetOutboundHeaders 配置附加到每个出站请求上的静态请求头
+// 作用于注册、注销请求以及注册中心推送的依赖更新
+// 参数:
+// - h: 请求头集合，传入nil表示清除已有配置
+func SetOutboundHeaders(h http.Header) {
+	outboundHeadersMu.Lock()
+	defer outboundHeadersMu.Unlock()
+	outboundHeaders = h.Clone()
+}
+
+// newRequest 创建出站HTTP请求并附加配置的静态请求头
+// 参数:
+// - ctx: 请求上下文，取消时请求随之中止
+// - method: HTTP方法
+// - url: 请求地址
+// - contentType: 请求体类型，为空时不设置
+// - body: 请求体
+// 返回:
+// - *http.Request: 构造好的请求
+// - error: 构造过程中的错误
+func newRequest(ctx context.Context, method, url, contentType string,
+	body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	outboundHeadersMu.RLock()
+	for name, values := range outboundHeaders {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	outboundHeadersMu.RUnlock()
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+// post 发送带有静态请求头的POST请求，除上下文参数外用法与http.Post相同
+func post(ctx context.Context, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := newRequest(ctx, http.MethodPost, url, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	return do(req)
+}
@@ -0,0 +1,226 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// headerRecorder 是记录最近一次请求头的httptest服务器
+type headerRecorder struct {
+	URL string
+
+	mu     sync.Mutex
+	header http.Header
+}
+
+func newHeaderRecorder(t *testing.T) *headerRecorder {
+	t.Helper()
+	rec := new(headerRecorder)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.header = r.Header.Clone()
+	}))
+	t.Cleanup(srv.Close)
+	rec.URL = srv.URL
+	return rec
+}
+
+func (rec *headerRecorder) Get(name string) string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.header.Get(name)
+}
+
+// withOutboundHeaders 在测试期间附加h中的请求头，测试结束时清除
+func withOutboundHeaders(t *testing.T, h http.Header) {
+	t.Helper()
+	SetOutboundHeaders(h)
+	t.Cleanup(func() { SetOutboundHeaders(nil) })
+}
+
+func TestSendPatchCarriesOutboundHeaders(t *testing.T) {
+	withOutboundHeaders(t, http.Header{"Baggage": {"tenant=blue"}, "X-Internal-Auth": {"secret"}})
+	rec := newHeaderRecorder(t)
+
+	r := newTestRegistry(httpNotifier{}, 1)
+	if err := r.sendPatch(patch{}, rec.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Get("Baggage"); got != "tenant=blue" {
+		t.Errorf("Baggage header = %q, want tenant=blue", got)
+	}
+	if got := rec.Get("X-Internal-Auth"); got != "secret" {
+		t.Errorf("X-Internal-Auth header = %q, want secret", got)
+	}
+	if got := rec.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+func TestRegisterServiceCarriesOutboundHeaders(t *testing.T) {
+	withOutboundHeaders(t, http.Header{"Baggage": {"tenant=green"}})
+	rec := newHeaderRecorder(t)
+	SetRegistryURL(rec.URL)
+	defer SetRegistryURL("")
+
+	if err := RegisterServiceContext(context.Background(), Registration{ServiceName: LogService, ServiceURL: "http://log"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Get("Baggage"); got != "tenant=green" {
+		t.Errorf("Baggage header on registration = %q, want tenant=green", got)
+	}
+}
+
+func TestClearedOutboundHeadersAreNotSent(t *testing.T) {
+	SetOutboundHeaders(http.Header{"Baggage": {"tenant=blue"}})
+	SetOutboundHeaders(nil)
+	rec := newHeaderRecorder(t)
+
+	r := newTestRegistry(httpNotifier{}, 1)
+	if err := r.sendPatch(patch{}, rec.URL); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.Get("Baggage"); got != "" {
+		t.Errorf("Baggage header = %q after clearing, want none", got)
+	}
+}
+
+// hangingRegistry 启动一个从不响应的注册中心，测试结束时释放挂起的请求
+func hangingRegistry(t *testing.T) {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	SetRegistryURL(srv.URL)
+	// 不带超时的客户端，只有注销自身的期限能让请求结束
+	SetHTTPClient(&http.Client{})
+	t.Cleanup(func() {
+		SetHTTPClient(nil)
+		SetRegistryURL("")
+		close(release)
+		srv.Close()
+	})
+}
+
+func TestShutdownServiceReturnsWhenRegistryHangs(t *testing.T) {
+	hangingRegistry(t)
+
+	start := time.Now()
+	err := ShutdownService("http://log")
+	if elapsed := time.Since(start); elapsed > DeregisterTimeout+time.Second {
+		t.Fatalf("ShutdownService took %v, want it bounded by %v", elapsed, DeregisterTimeout)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ShutdownService = %v, want a deadline error", err)
+	}
+}
+
+func TestDeregisterServiceContextHonorsDeadline(t *testing.T) {
+	hangingRegistry(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := DeregisterServiceContext(ctx, "http://log", ReasonShutdown); err == nil {
+		t.Fatal("DeregisterServiceContext against a hung registry succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DeregisterServiceContext took %v, want it to stop at the 50ms deadline", elapsed)
+	}
+}
+
+func TestHTTPClientTimeoutAppliesToOutboundRequests(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+	SetHTTPClient(&http.Client{Timeout: 50 * time.Millisecond})
+	SetRegistryURL(slow.URL)
+	t.Cleanup(func() {
+		SetHTTPClient(nil)
+		SetRegistryURL("")
+	})
+
+	isTimeout := func(err error) bool {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+
+	r := newTestRegistry(httpNotifier{}, 1)
+	start := time.Now()
+	if err := r.sendPatch(patch{}, slow.URL); !isTimeout(err) {
+		t.Errorf("sendPatch to a hung dependent = %v, want a timeout error", err)
+	}
+	if err := RegisterService(Registration{ServiceName: LogService, ServiceURL: "http://log"}); !isTimeout(err) {
+		t.Errorf("RegisterService to a hung registry = %v, want a timeout error", err)
+	}
+	if err := ShutdownService("http://log"); !isTimeout(err) {
+		t.Errorf("ShutdownService to a hung registry = %v, want a timeout error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("three timed-out requests took %v, want them bounded by the client timeout", elapsed)
+	}
+}
+
+func TestRegistrationPostsToConfiguredRegistryURL(t *testing.T) {
+	type request struct {
+		method, path string
+		reg          Registration
+	}
+	requests := make(chan request, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reg Registration
+		json.NewDecoder(r.Body).Decode(&reg)
+		requests <- request{r.Method, r.URL.Path, reg}
+	}))
+	defer srv.Close()
+	SetRegistryURL(srv.URL + "/custom/services")
+	t.Cleanup(func() { SetRegistryURL("") })
+
+	if err := RegisterService(Registration{ServiceName: LogService, ServiceURL: "http://log"}); err != nil {
+		t.Fatal(err)
+	}
+	got := <-requests
+	if got.method != http.MethodPost || got.path != "/custom/services" || got.reg.ServiceURL != "http://log" {
+		t.Errorf("registration = %v %v %+v, want POST /custom/services for http://log", got.method, got.path, got.reg)
+	}
+
+	if err := ShutdownService("http://log"); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-requests; got.method != http.MethodDelete || got.path != "/custom/services" {
+		t.Errorf("deregistration = %v %v, want DELETE /custom/services", got.method, got.path)
+	}
+}
+
+func TestRegistryURLFromEnvironment(t *testing.T) {
+	t.Setenv(RegistryURLEnv, "http://registry.example:3000/services")
+	SetRegistryURL("")
+	t.Cleanup(func() { SetRegistryURL("") })
+	if got := servicesURL(); got != "http://registry.example:3000/services" {
+		t.Errorf("servicesURL() = %v, want the %v value", got, RegistryURLEnv)
+	}
+
+	t.Setenv(RegistryURLEnv, "")
+	SetRegistryURL("")
+	if got := servicesURL(); got != ServicesURL {
+		t.Errorf("servicesURL() = %v, want the default %v", got, ServicesURL)
+	}
+}
@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Validate 在不启动任何服务的情况下检查一组计划运行的服务能否组成完整的系统
+// 纯内存计算，不发送任何HTTP请求，适合在部署前或测试中验证服务编排
+// 检查内容:
+// 1. 每个注册信息本身合法(见Registration.Validate)
+// 2. 每个RequireServices中的服务都至少有一个提供者
+// 3. 服务之间的依赖关系不构成环，服务依赖自身类型不视为环(见ExcludeSelf)
+// 参数:
+// - regs: 计划运行的全部服务的注册信息
+// 返回:
+// - []error: 发现的所有问题，没有问题时返回nil
+func Validate(regs []Registration) []error {
+	var errs []error
+
+	// 按服务名称汇总提供者和依赖，同一服务的多个实例合并为一个节点
+	provided := make(map[ServiceName]bool, len(regs))
+	requires := make(map[ServiceName][]ServiceName, len(regs))
+	for _, r := range regs {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+		provided[r.ServiceName] = true
+		for _, req := range r.RequireServices {
+			if !slices.Contains(requires[r.ServiceName], req) {
+				requires[r.ServiceName] = append(requires[r.ServiceName], req)
+			}
+		}
+	}
+
+	names := make([]ServiceName, 0, len(requires))
+	for name := range requires {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	// 缺少提供者的依赖
+	for _, name := range names {
+		for _, req := range requires[name] {
+			if !provided[req] {
+				errs = append(errs, fmt.Errorf("%v requires %v, but no service provides it", name, req))
+			}
+		}
+	}
+
+	// 深度优先搜索检测依赖环，每个环只报告一次
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[ServiceName]int, len(requires))
+	var path []ServiceName
+	var visit func(name ServiceName)
+	visit = func(name ServiceName) {
+		state[name] = visiting
+		path = append(path, name)
+		for _, req := range requires[name] {
+			if req == name {
+				continue
+			}
+			switch state[req] {
+			case unvisited:
+				visit(req)
+			case visiting:
+				cycle := append(slices.Clone(path[slices.Index(path, req):]), req)
+				errs = append(errs, fmt.Errorf("dependency cycle: %v", joinNames(cycle)))
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+	}
+	for _, name := range names {
+		if state[name] == unvisited {
+			visit(name)
+		}
+	}
+	return errs
+}
+
+// joinNames 把服务名称列表格式化为"A -> B -> A"的形式
+func joinNames(names []ServiceName) string {
+	parts := make([]string, len(names))
+	for i, n := range names {
+		parts[i] = string(n)
+	}
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// planned 返回name的一个合法注册信息，依赖requires中的服务
+func planned(name ServiceName, requires ...ServiceName) Registration {
+	url := "http://" + strings.ToLower(string(name))
+	return Registration{
+		ServiceName:      name,
+		ServiceURL:       url,
+		ServiceUpdateURL: url + "/services",
+		RequireServices:  requires,
+	}
+}
+
+func TestValidateSatisfiableGraph(t *testing.T) {
+	errs := Validate([]Registration{
+		planned(LogService),
+		planned(GradingService, LogService),
+		planned(PortalService, LogService, GradingService),
+		// 同一服务的第二个实例，以及依赖自身类型的服务都不是问题
+		planned(LogService, LogService),
+	})
+	if errs != nil {
+		t.Errorf("Validate = %v, want nil", errs)
+	}
+}
+
+func TestValidateReportsMissingDependency(t *testing.T) {
+	errs := Validate([]Registration{
+		planned(GradingService, LogService),
+		planned(PortalService, GradingService),
+	})
+	if len(errs) != 1 || errs[0].Error() != "GradingService requires LogService, but no service provides it" {
+		t.Errorf("Validate = %v, want only the missing log service", errs)
+	}
+}
+
+func TestValidateReportsCycle(t *testing.T) {
+	errs := Validate([]Registration{
+		planned(LogService, PortalService),
+		planned(GradingService, LogService),
+		planned(PortalService, GradingService),
+	})
+	if len(errs) != 1 {
+		t.Fatalf("Validate = %v, want exactly one cycle", errs)
+	}
+	if got := errs[0].Error(); got != "dependency cycle: GradingService -> LogService -> PortalService -> GradingService" {
+		t.Errorf("cycle = %q, want the full loop starting at the first service by name", got)
+	}
+}
+
+func TestValidateReportsInvalidRegistration(t *testing.T) {
+	bad := planned(LogService)
+	bad.ServiceURL = "localhost:4000"
+	errs := Validate([]Registration{bad, planned(GradingService, LogService)})
+	if len(errs) != 1 || !strings.Contains(fmt.Sprint(errs), "invalid ServiceURL") {
+		t.Errorf("Validate = %v, want the invalid ServiceURL reported", errs)
+	}
+}
@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// DefaultWatchdogInterval 是检查服务是否仍在注册表中的默认间隔
+const DefaultWatchdogInterval = 30 * time.Second
+
+// WatchRegistration 周期性地确认服务仍在注册中心，不在时重新注册
+// 注册中心重启且没有恢复注册表时，服务的注册信息会丢失，
+// 依赖方再也发现不了它；看门狗使服务在一个检查间隔内自动恢复注册
+// 业务流程:
+// 1. 每隔interval通过GET /services获取注册表
+// 2. 注册表中没有r.ServiceURL时调用RegisterServiceContext重新注册
+// 3. 注册中心不可达时记录日志，下一个间隔再试
+// 此函数会阻塞直到ctx被取消，通常在单独的goroutine中运行
+// 参数:
+// - ctx: 服务的生命周期上下文，服务关闭时取消
+// - r: 服务的注册信息
+// - interval: 检查间隔
+func WatchRegistration(ctx context.Context, r Registration, interval time.Duration) {
+	for {
+		prov.mutex.RLock()
+		clock := prov.clock
+		prov.mutex.RUnlock()
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(interval):
+		}
+
+		ok, err := isRegistered(ctx, r.ServiceURL)
+		if err != nil {
+			log.Printf("watchdog: %v", err)
+			continue
+		}
+		// 服务正在关闭时不再重新注册，避免与注销请求竞争
+		if ok || ctx.Err() != nil {
+			continue
+		}
+		log.Printf("watchdog: %v at %v missing from registry, re-registering",
+			r.ServiceName, r.ServiceURL)
+		if err := RegisterServiceContext(ctx, r); err != nil {
+			log.Printf("watchdog: re-register %v failed: %v", r.ServiceName, err)
+		}
+	}
+}
+
+// isRegistered 查询注册中心的注册表，判断serviceURL是否已注册
+// 参数:
+// - ctx: 请求上下文
+// - serviceURL: 服务URL
+// 返回:
+// - bool: 是否已注册
+// - error: 注册中心不可达或响应无法解析时返回错误
+func isRegistered(ctx context.Context, serviceURL string) (bool, error) {
+	regs, err := ListRegistrations(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, reg := range regs {
+		if reg.ServiceURL == serviceURL {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListRegistrations 通过GET /services从注册中心获取完整的注册表
+// 供无法接收推送(没有挂载更新处理器)的客户端轮询服务发现
+// 参数:
+// - ctx: 请求上下文
+// 返回:
+// - []Registration: 注册中心当前的全部注册信息
+// - error: 注册中心不可达或响应无法解析时返回错误
+func ListRegistrations(ctx context.Context) ([]Registration, error) {
+	req, err := newRegistryRequest(ctx, http.MethodGet, servicesURL(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("registry responded with code %v", res.StatusCode)
+	}
+	var regs []Registration
+	if err := json.NewDecoder(res.Body).Decode(&regs); err != nil {
+		return nil, err
+	}
+	return regs, nil
+}
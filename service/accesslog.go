@@ -0,0 +1,25 @@
+package service
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// accessLog 是记录访问日志的中间件
+// 每个请求结束后输出一行: 方法 路径 状态码 耗时
+// 参数:
+// - next: 被包装的处理器
+// 返回:
+// - http.Handler: 包装后的处理器
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(sr, r)
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+		log.Printf("%s %s %d %v", r.Method, r.URL.Path, sr.status, time.Since(start))
+	})
+}
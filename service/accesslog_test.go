@@ -0,0 +1,42 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogRecordsStatusAndPath(t *testing.T) {
+	buf := captureLog(t)
+	h := accessLog(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/students/7/grades", nil))
+
+	if line := buf.String(); !strings.Contains(line, "POST /students/7/grades 418 ") {
+		t.Errorf("log = %q, want method, path and status 418", line)
+	}
+}
+
+func TestAccessLogDefaultsToOK(t *testing.T) {
+	buf := captureLog(t)
+	h := accessLog(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/students", nil))
+
+	if line := buf.String(); !strings.Contains(line, "GET /students 200 ") {
+		t.Errorf("log = %q, want status 200 when the handler never called WriteHeader", line)
+	}
+}
+
+func TestWithAccessLogEnablesMiddleware(t *testing.T) {
+	var o options
+	WithAccessLog()(&o)
+	if !o.accessLog {
+		t.Error("WithAccessLog did not enable the access log")
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSlowCallThreshold 是慢调用阈值的默认值
+const DefaultSlowCallThreshold = time.Second
+
+// slowCallThreshold 保存慢调用阈值(纳秒)，耗时达到该值的下游调用会被记录
+var slowCallThreshold atomic.Int64
+
+func init() {
+	slowCallThreshold.Store(int64(DefaultSlowCallThreshold))
+}
+
+// SetSlowCallThreshold 设置慢调用阈值
+// 参数:
+// - d: 阈值，小于等于0表示关闭慢调用日志
+func SetSlowCallThreshold(d time.Duration) {
+	slowCallThreshold.Store(int64(d))
+}
+
+// Call 通过服务发现调用下游服务
+// 这是服务间调用的通用入口，调用方无需关心下游服务的具体地址
+// 业务流程:
+// 1. 通过注册中心客户端获取目标服务的URL
+// 2. 构造并发送HTTP请求
+// 3. 耗时超过慢调用阈值时，通过日志(会被转发到中央日志服务)记录警告
+// 参数:
+// - ctx: 请求上下文，用于取消和超时控制
+// - name: 目标服务名称
+// - method: HTTP方法
+// - path: 请求路径，例如"/students"
+// - body: 请求体，可为nil
+// 返回:
+// - *http.Response: 下游服务的响应，调用方负责关闭Body
+// - error: 调用过程中的错误
+func Call(ctx context.Context, name registry.ServiceName, method, path string,
+	body io.Reader) (*http.Response, error) {
+	serviceURL, err := registry.GetProvider(name)
+	if err != nil {
+		return nil, err
+	}
+
+	url := serviceURL + path
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	res, err := registry.HTTPClient().Do(req)
+	elapsed := time.Since(start)
+	if threshold := time.Duration(slowCallThreshold.Load()); threshold > 0 && elapsed >= threshold {
+		log.Printf("slow call to %v: %s %s took %v", name, method, url, elapsed)
+	}
+	return res, err
+}
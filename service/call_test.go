@@ -0,0 +1,74 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withProvider 通过更新处理器把url登记为name的提供者，测试结束时移除
+func withProvider(t *testing.T, name registry.ServiceName, url string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	if err := registry.RegisterUpdateHandler(mux, registry.Registration{ServiceUpdateURL: "http://test/services"}); err != nil {
+		t.Fatal(err)
+	}
+	send := func(field string) {
+		body := fmt.Sprintf(`{%q:[{"Name":%q,"URL":%q}]}`, field, name, url)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update handler returned %d for %s", rec.Code, body)
+		}
+	}
+	send("Added")
+	t.Cleanup(func() { send("Removed") })
+}
+
+// captureLog 在测试期间把标准库日志输出写入返回的缓冲区
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	saved := log.Writer()
+	log.SetOutput(buf)
+	t.Cleanup(func() { log.SetOutput(saved) })
+	return buf
+}
+
+func TestCallLogsOnlySlowCalls(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer fast.Close()
+	withProvider(t, "Slow Service", slow.URL)
+	withProvider(t, "Fast Service", fast.URL)
+
+	SetSlowCallThreshold(25 * time.Millisecond)
+	defer SetSlowCallThreshold(DefaultSlowCallThreshold)
+	logged := captureLog(t)
+
+	for _, name := range []registry.ServiceName{"Fast Service", "Slow Service"} {
+		res, err := Call(context.Background(), name, http.MethodGet, "/ping", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	out := logged.String()
+	if !strings.Contains(out, "slow call to Slow Service: GET "+slow.URL+"/ping took") {
+		t.Errorf("log output %q has no slow-call warning for the slow service", out)
+	}
+	if strings.Contains(out, "Fast Service") {
+		t.Errorf("log output %q warns about the fast service", out)
+	}
+}
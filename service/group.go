@@ -0,0 +1,72 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Group 在同一进程中运行多个服务，用于测试和单进程演示
+// 组内的服务不单独监听SIGINT/SIGTERM，而是在Wait收到一次取消后，
+// 按启动的逆序依次关闭，所有服务共用一个关闭期限
+// 例如先启动日志服务再启动成绩服务，关闭时先关闭成绩服务，它最后的日志仍能送达日志服务
+type Group struct {
+	// timeout 是关闭全部服务的共同期限
+	timeout time.Duration
+
+	// mu 保护members
+	mu      sync.Mutex
+	members []*instance
+}
+
+// NewGroup 创建一个服务组
+// 参数:
+// - timeout: 关闭全部服务的共同期限，小于等于0时使用DefaultShutdownTimeout
+func NewGroup(timeout time.Duration) *Group {
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	return &Group{timeout: timeout}
+}
+
+// Start 启动一个服务并加入组，参数与包级函数Start相同(不需要ctx)
+// 返回:
+// - context.Context: 在该服务关闭后被取消的上下文
+// - error: 启动过程中的错误；HTTP服务器已启动但注册失败时，服务仍然加入组，由组负责关闭
+func (g *Group) Start(reg registry.Registration, host, port string,
+	registerHandlesFunc func(mux *http.ServeMux), opts ...Option) (context.Context, error) {
+	o := newOptions(opts)
+	o.groupManaged = true
+	inst, err := start(context.Background(), reg, host, port, registerHandlesFunc, o)
+	if inst == nil {
+		return context.Background(), err
+	}
+	g.mu.Lock()
+	g.members = append(g.members, inst)
+	g.mu.Unlock()
+	return inst.ctx, err
+}
+
+// Wait 阻塞直到ctx被取消，然后关闭组内的全部服务
+// 通常传入signal.NotifyContext返回的上下文，使一次Ctrl+C关闭所有服务
+func (g *Group) Wait(ctx context.Context) {
+	<-ctx.Done()
+	g.Shutdown()
+}
+
+// Shutdown 按启动的逆序依次关闭组内的全部服务，全部关闭(或共同期限到期)后返回
+func (g *Group) Shutdown() {
+	g.mu.Lock()
+	members := append([]*instance(nil), g.members...)
+	g.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+	for i := len(members) - 1; i >= 0; i-- {
+		log.Printf("shutting down %v", members[i].name)
+		members[i].shutdown(ctx)
+	}
+}
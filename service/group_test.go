@@ -0,0 +1,103 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startRegistry 在进程内启动注册中心，测试结束时关闭
+func startRegistry(t *testing.T) {
+	t.Helper()
+	srv := httptest.NewServer(&registry.RegistryService{})
+	registry.SetRegistryURL(srv.URL)
+	t.Cleanup(func() {
+		registry.SetRegistryURL("")
+		srv.Close()
+	})
+}
+
+// registeredNames 返回注册中心当前登记的服务名称
+func registeredNames(t *testing.T) []registry.ServiceName {
+	t.Helper()
+	regs, err := registry.ListRegistrations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []registry.ServiceName
+	for _, r := range regs {
+		names = append(names, r.ServiceName)
+	}
+	return names
+}
+
+func TestGroupShutsDownInReverseOrder(t *testing.T) {
+	startRegistry(t)
+
+	var mu sync.Mutex
+	var stopped []registry.ServiceName
+	recordStop := func(name registry.ServiceName) Option {
+		return WithShutdownHook(func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			stopped = append(stopped, name)
+			return nil
+		})
+	}
+	noRoutes := func(*http.ServeMux) {}
+
+	g := NewGroup(5 * time.Second)
+	logCtx, err := g.Start(registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+	}, "localhost", "0", noRoutes, recordStop(registry.LogService))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gradingCtx, err := g.Start(registry.Registration{
+		ServiceName:      registry.GradingService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+		RequireServices:  []registry.ServiceName{registry.LogService},
+	}, "localhost", "0", noRoutes, recordStop(registry.GradingService))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := registeredNames(t)
+	if !slices.Contains(names, registry.LogService) || !slices.Contains(names, registry.GradingService) {
+		t.Fatalf("registered services = %v, want both log and grading", names)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		g.Wait(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("group did not shut down")
+	}
+
+	if names := registeredNames(t); len(names) != 0 {
+		t.Fatalf("services still registered after shutdown: %v", names)
+	}
+	for _, c := range []context.Context{logCtx, gradingCtx} {
+		if c.Err() == nil {
+			t.Fatal("service context not cancelled after shutdown")
+		}
+	}
+	want := []registry.ServiceName{registry.GradingService, registry.LogService}
+	if !slices.Equal(stopped, want) {
+		t.Fatalf("shutdown order = %v, want %v", stopped, want)
+	}
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxDecompressedBytes 是解压后请求体大小的默认上限
+// 用于防止压缩炸弹：很小的压缩数据解压后可能占用巨大的内存
+const DefaultMaxDecompressedBytes = 10 << 20
+
+// gzipRequestBody 是透明解压请求体的中间件
+// 当请求带有Content-Encoding: gzip时，在处理函数读取之前替换为解压后的请求体，
+// 处理函数无需关心请求是否被压缩
+// 参数:
+// - next: 被包装的处理器
+// - limit: 解压后请求体的最大字节数，超出后读取会返回错误
+// 返回:
+// - http.Handler: 包装后的处理器
+func gzipRequestBody(next http.Handler, limit int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			// 请求声明了gzip但内容不是合法的gzip数据
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer zr.Close()
+
+		// 解压后的请求体受limit限制，超出时处理函数读取会得到错误
+		r.Body = http.MaxBytesReader(w, zr, limit)
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
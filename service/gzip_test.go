@@ -0,0 +1,97 @@
+package service
+
+import (
+	"My_mimiDistributed/log"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gzipped 返回data经gzip压缩后的内容
+func gzipped(t *testing.T, data []byte) *bytes.Buffer {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestGzipBodyWrittenByLogService(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := log.Run(path, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	log.RegisterHandlers(mux)
+	h := gzipRequestBody(mux, DefaultMaxDecompressedBytes)
+
+	req := httptest.NewRequest(http.MethodPost, "/log", gzipped(t, []byte("compressed hello")))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST gzipped /log = %v, want %v", rec.Code, http.StatusOK)
+	}
+
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "compressed hello") {
+		t.Errorf("log file contains %q, want the decompressed message", data)
+	}
+}
+
+// bodyLength 是返回读取到的请求体长度的处理器，读取失败时返回413
+var bodyLength = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Write([]byte(r.Header.Get("Content-Encoding") + ":" + buf.String()))
+})
+
+func TestGzipBodyLimitStopsZipBomb(t *testing.T) {
+	// 1MB的零压缩后只有约1KB
+	bomb := gzipped(t, make([]byte, 1<<20))
+	req := httptest.NewRequest(http.MethodPost, "/", bomb)
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	gzipRequestBody(bodyLength, 1024).ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized decompressed body = %v, want the read to fail", rec.Code)
+	}
+}
+
+func TestGzipBodyRejectsInvalidData(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	gzipRequestBody(bodyLength, DefaultMaxDecompressedBytes).ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid gzip body = %v, want %v", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGzipBodyPassesPlainRequests(t *testing.T) {
+	rec := httptest.NewRecorder()
+	gzipRequestBody(bodyLength, DefaultMaxDecompressedBytes).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("plain")))
+	if got := rec.Body.String(); got != ":plain" {
+		t.Errorf("plain request body = %q, want it passed through unchanged", got)
+	}
+}
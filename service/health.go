@@ -0,0 +1,142 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthProbeTimeout 是深度健康检查中探测单个依赖的超时时间
+const healthProbeTimeout = 2 * time.Second
+
+const (
+	// StatusOK 表示服务或依赖健康
+	StatusOK = "ok"
+
+	// StatusDegraded 表示服务本身可用，但至少有一个依赖不健康
+	StatusDegraded = "degraded"
+
+	// StatusUnavailable 表示依赖不可用
+	StatusUnavailable = "unavailable"
+)
+
+// DependencyHealth 是单个依赖实例的健康状态
+type DependencyHealth struct {
+	Service registry.ServiceName
+	URL     string `json:",omitempty"`
+	Status  string
+	Error   string `json:",omitempty"`
+}
+
+// HealthReport 是深度健康检查的汇总报告
+type HealthReport struct {
+	// Status 是整体状态，所有依赖健康时为ok，否则为degraded
+	Status       string
+	Dependencies []DependencyHealth
+}
+
+// registerHealthHandlers 在mux上注册健康检查接口
+// - GET /health: 服务自身的健康状态，注册中心的心跳检查也使用此接口
+// - GET /health/deep: 同时探测所有已发现依赖的/health并汇总结果
+// 参数:
+// - mux: 服务的路由器
+// - reg: 服务注册信息，用于确定需要探测的依赖
+// - health: 服务自身的健康检查函数，为nil时总是视为健康
+func registerHealthHandlers(mux *http.ServeMux, reg registry.Registration, health func() error) {
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		if health != nil {
+			if err := health(); err != nil {
+				log.Println("health check failed: ", err)
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/health/deep", func(w http.ResponseWriter, r *http.Request) {
+		report := deepHealth(r.Context(), reg.RequireServices)
+		data, err := json.Marshal(report)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != StatusOK {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		w.Write(data)
+	})
+}
+
+// deepHealth 并发探测每个依赖的所有已知实例，汇总为健康报告
+// 依赖没有任何可用实例时同样视为不健康
+// 参数:
+// - ctx: 请求上下文
+// - deps: 需要探测的依赖服务
+// 返回:
+// - HealthReport: 汇总后的健康报告
+func deepHealth(ctx context.Context, deps []registry.ServiceName) HealthReport {
+	known := registry.DumpProviders()
+	report := HealthReport{Status: StatusOK, Dependencies: make([]DependencyHealth, 0)}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	record := func(h DependencyHealth) {
+		mu.Lock()
+		defer mu.Unlock()
+		report.Dependencies = append(report.Dependencies, h)
+		if h.Status != StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	for _, dep := range deps {
+		urls := known[dep]
+		if len(urls) == 0 {
+			record(DependencyHealth{Service: dep, Status: StatusUnavailable,
+				Error: "no providers available"})
+			continue
+		}
+		for _, u := range urls {
+			wg.Add(1)
+			go func(dep registry.ServiceName, u string) {
+				defer wg.Done()
+				h := DependencyHealth{Service: dep, URL: u, Status: StatusOK}
+				if err := probeHealth(ctx, u); err != nil {
+					h.Status = StatusUnavailable
+					h.Error = err.Error()
+				}
+				record(h)
+			}(dep, u)
+		}
+	}
+	wg.Wait()
+	return report
+}
+
+// probeHealth 请求服务的/health接口，超时或响应码不是200时返回错误
+func probeHealth(ctx context.Context, serviceURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, healthProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, serviceURL+"/health", nil)
+	if err != nil {
+		return err
+	}
+	res, err := registry.HTTPClient().Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check responded with code %v", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// healthMux 返回注册了健康检查接口、依赖为deps的路由器
+func healthMux(health func() error, deps ...registry.ServiceName) *http.ServeMux {
+	mux := http.NewServeMux()
+	registerHealthHandlers(mux, registry.Registration{RequireServices: deps}, health)
+	return mux
+}
+
+func TestDeepHealthReportsEachDependency(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer healthy.Close()
+	unhealthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unhealthy.Close()
+	withProvider(t, "Healthy Service", healthy.URL)
+	withProvider(t, "Unhealthy Service", unhealthy.URL)
+
+	rec := httptest.NewRecorder()
+	mux := healthMux(nil, "Healthy Service", "Unhealthy Service", "Missing Service")
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/deep", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /health/deep = %v, want %v", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	var report HealthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusDegraded {
+		t.Errorf("overall status = %q, want %q", report.Status, StatusDegraded)
+	}
+	status := make(map[registry.ServiceName]DependencyHealth)
+	for _, h := range report.Dependencies {
+		status[h.Service] = h
+	}
+	if h := status["Healthy Service"]; h.Status != StatusOK || h.URL != healthy.URL {
+		t.Errorf("healthy dependency = %+v, want ok at %v", h, healthy.URL)
+	}
+	if h := status["Unhealthy Service"]; h.Status != StatusUnavailable || h.Error == "" {
+		t.Errorf("unhealthy dependency = %+v, want unavailable with an error", h)
+	}
+	if h := status["Missing Service"]; h.Status != StatusUnavailable {
+		t.Errorf("dependency without providers = %+v, want unavailable", h)
+	}
+}
+
+func TestDeepHealthOKWhenAllDependenciesHealthy(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer healthy.Close()
+	withProvider(t, "Healthy Service", healthy.URL)
+
+	rec := httptest.NewRecorder()
+	healthMux(nil, "Healthy Service").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/deep", nil))
+	var report HealthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("GET /health/deep = %v with status %q, want 200 ok", rec.Code, report.Status)
+	}
+}
+
+func TestHealthReflectsLocalCheck(t *testing.T) {
+	rec := httptest.NewRecorder()
+	healthMux(func() error { return errors.New("database down") }).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /health with a failing check = %v, want %v", rec.Code, http.StatusServiceUnavailable)
+	}
+}
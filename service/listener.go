@@ -0,0 +1,95 @@
+package service
+
+import (
+	"net"
+	"sync"
+)
+
+// limitListener 包装net.Listener，限制同时存在的已接受连接数
+// 它使用带缓冲的通道作为信号量：每个连接占用一个槽位，连接关闭时归还
+type limitListener struct {
+	net.Listener
+
+	// sem 是信号量，容量即最大连接数
+	sem chan struct{}
+
+	// reject 为true时超出上限的连接被立即关闭，否则Accept阻塞排队
+	reject bool
+
+	// done 在监听器关闭时被关闭，用于唤醒阻塞在信号量上的Accept
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newLimitListener 创建限制并发连接数的监听器
+// 参数:
+// - l: 底层监听器
+// - n: 最大并发连接数
+// - reject: 超出上限时是否直接拒绝
+func newLimitListener(l net.Listener, n int, reject bool) net.Listener {
+	return &limitListener{
+		Listener: l,
+		sem:      make(chan struct{}, n),
+		reject:   reject,
+		done:     make(chan struct{}),
+	}
+}
+
+// Accept 接受一个新连接
+// 排队模式下先获取槽位再接受连接，未获取到槽位的连接留在内核队列中等待
+// 拒绝模式下先接受连接，若没有空闲槽位则立即关闭该连接并继续等待下一个
+func (l *limitListener) Accept() (net.Conn, error) {
+	if l.reject {
+		for {
+			c, err := l.Listener.Accept()
+			if err != nil {
+				return nil, err
+			}
+			select {
+			case l.sem <- struct{}{}:
+				return &limitConn{Conn: c, release: l.release}, nil
+			default:
+				// 没有空闲槽位，直接关闭连接
+				c.Close()
+			}
+		}
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		l.release()
+		return nil, err
+	}
+	return &limitConn{Conn: c, release: l.release}, nil
+}
+
+// Close 关闭监听器，并唤醒所有排队中的Accept
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() { close(l.done) })
+	return err
+}
+
+// release 归还一个连接槽位
+func (l *limitListener) release() {
+	<-l.sem
+}
+
+// limitConn 包装net.Conn，确保连接关闭时只归还一次槽位
+type limitConn struct {
+	net.Conn
+	releaseOnce sync.Once
+	release     func()
+}
+
+// Close 关闭连接并归还槽位
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.releaseOnce.Do(c.release)
+	return err
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// acceptAll 在后台接受ln上的连接，并通过返回的通道交给测试
+func acceptAll(t *testing.T, ln net.Listener) <-chan net.Conn {
+	t.Helper()
+	accepted := make(chan net.Conn, 10)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return accepted
+}
+
+// dial 连接ln，测试结束时关闭连接
+func dial(t *testing.T, ln net.Listener) net.Conn {
+	t.Helper()
+	c, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestLimitListenerQueuesExcessConnections(t *testing.T) {
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newLimitListener(base, 2, false)
+	accepted := acceptAll(t, ln)
+
+	for i := 0; i < 3; i++ {
+		dial(t, ln)
+	}
+	first := <-accepted
+	<-accepted
+	select {
+	case <-accepted:
+		t.Fatal("third connection accepted while two were open, want it queued")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 归还一个槽位后，排队的连接被接受
+	first.Close()
+	select {
+	case <-accepted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued connection was not accepted after a slot was released")
+	}
+}
+
+func TestLimitListenerRejectsExcessConnections(t *testing.T) {
+	base, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := newLimitListener(base, 1, true)
+	accepted := acceptAll(t, ln)
+
+	dial(t, ln)
+	<-accepted
+
+	// 超出上限的连接被服务端直接关闭
+	excess := dial(t, ln)
+	excess.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := excess.Read(make([]byte, 1)); err == nil {
+		t.Fatal("excess connection was not closed")
+	} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("excess connection is still open, want it refused")
+	}
+	select {
+	case <-accepted:
+		t.Fatal("excess connection was handed to the server")
+	default:
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// MetricsSnapshot 是某一时刻请求计数器的副本
+type MetricsSnapshot struct {
+	// Requests 是处理过的请求总数
+	Requests uint64
+
+	// Errors 是响应状态码为5xx的请求数
+	Errors uint64
+
+	// PathRequests 是按路由模式(例如/students/)统计的请求数
+	// 使用路由模式而不是原始路径，避免/students/1、/students/2等路径使计数器无限增长
+	PathRequests map[string]uint64
+}
+
+// metricsRegistry 保存服务的请求计数器
+type metricsRegistry struct {
+	mu       sync.Mutex
+	requests uint64
+	errors   uint64
+	paths    map[string]uint64
+}
+
+// metrics 是服务进程内唯一的计数器集合
+var metrics = &metricsRegistry{paths: make(map[string]uint64)}
+
+// Metrics 返回当前请求计数器的副本，供程序内部读取
+func Metrics() MetricsSnapshot {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	paths := make(map[string]uint64, len(metrics.paths))
+	for p, n := range metrics.paths {
+		paths[p] = n
+	}
+	return MetricsSnapshot{
+		Requests:     metrics.requests,
+		Errors:       metrics.errors,
+		PathRequests: paths,
+	}
+}
+
+// record 记录一次请求
+func (m *metricsRegistry) record(pattern string, status int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests++
+	if status >= 500 {
+		m.errors++
+	}
+	m.paths[pattern]++
+}
+
+// statusRecorder 包装http.ResponseWriter，记录处理函数写入的状态码
+// 供请求计数和访问日志中间件使用
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	return sr.ResponseWriter.Write(b)
+}
+
+// Unwrap 使http.NewResponseController能找到底层的ResponseWriter
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// countRequests 是统计请求数的中间件
+// 路由模式在ServeMux匹配后才能得到，所以在处理函数返回后记录
+// 参数:
+// - next: 被包装的处理器
+// 返回:
+// - http.Handler: 包装后的处理器
+func countRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sr := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(sr, r)
+		if sr.status == 0 {
+			sr.status = http.StatusOK
+		}
+		pattern := r.Pattern
+		if pattern == "" {
+			pattern = "unmatched"
+		}
+		metrics.record(pattern, sr.status)
+	})
+}
+
+// registerMetricsHandler 在mux上注册GET /metrics，以Prometheus文本格式输出计数器
+func registerMetricsHandler(mux *http.ServeMux) {
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		m := Metrics()
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(w, "# HELP http_requests_total Total number of HTTP requests handled.")
+		fmt.Fprintln(w, "# TYPE http_requests_total counter")
+		fmt.Fprintf(w, "http_requests_total %d\n", m.Requests)
+
+		fmt.Fprintln(w, "# HELP http_request_errors_total Number of HTTP requests answered with a 5xx status.")
+		fmt.Fprintln(w, "# TYPE http_request_errors_total counter")
+		fmt.Fprintf(w, "http_request_errors_total %d\n", m.Errors)
+
+		fmt.Fprintln(w, "# HELP http_requests_by_path_total Number of HTTP requests per route pattern.")
+		fmt.Fprintln(w, "# TYPE http_requests_by_path_total counter")
+		paths := make([]string, 0, len(m.PathRequests))
+		for p := range m.PathRequests {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			fmt.Fprintf(w, "http_requests_by_path_total{path=%q} %d\n", p, m.PathRequests[p])
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withFreshMetrics 在测试期间使用空的计数器，测试结束后恢复
+func withFreshMetrics(t *testing.T) {
+	t.Helper()
+	saved := metrics
+	metrics = &metricsRegistry{paths: make(map[string]uint64)}
+	t.Cleanup(func() { metrics = saved })
+}
+
+func TestMetricsCountsRequests(t *testing.T) {
+	withFreshMetrics(t)
+	mux := http.NewServeMux()
+	registerMetricsHandler(mux)
+	mux.HandleFunc("/students/", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("/fail", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(countRequests(mux))
+	defer srv.Close()
+
+	for _, path := range []string{"/students/1", "/students/2", "/fail", "/missing"} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	m := Metrics()
+	if m.Requests != 4 || m.Errors != 1 {
+		t.Errorf("Metrics() = %+v, want 4 requests and 1 error", m)
+	}
+	if n := m.PathRequests["/students/"]; n != 2 {
+		t.Errorf("/students/ count = %v, want 2 for both ids", n)
+	}
+
+	res, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := res.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+	// 计数在处理函数返回后记录，抓取本身不计入输出
+	body := string(data)
+	for _, want := range []string{
+		"http_requests_total 4\n",
+		"http_request_errors_total 1\n",
+		`http_requests_by_path_total{path="/students/"} 2` + "\n",
+		`http_requests_by_path_total{path="/fail"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("/metrics does not contain %q:\n%s", want, body)
+		}
+	}
+}
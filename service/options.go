@@ -0,0 +1,195 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"context"
+	"crypto/tls"
+	"time"
+)
+
+// DefaultShutdownTimeout 是关闭服务时等待进行中请求完成的默认时长
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Option 是Start的可选配置项
+// 采用函数式选项模式：Start原有参数保持不变，新增能力通过Option按需开启
+type Option func(*options)
+
+// options 汇总服务启动时的所有可选配置
+type options struct {
+	// maxConns 限制服务同时持有的连接数，0表示不限制
+	maxConns int
+
+	// rejectExcessConns 为true时，超出maxConns的连接会被立即关闭
+	// 为false(默认)时，超出的连接留在内核的监听队列中排队，直到有连接释放
+	rejectExcessConns bool
+
+	// maxDecompressedBytes 是gzip请求体解压后的大小上限
+	maxDecompressedBytes int64
+
+	// registryPreflight 为true时，Start在绑定端口前先确认注册中心可达
+	registryPreflight bool
+
+	// reusePort 为true时，监听套接字启用SO_REUSEPORT
+	reusePort bool
+
+	// health 是服务自身的健康检查函数，由GET /health调用
+	health func() error
+
+	// stdinShutdown 为true时，在控制台按回车也会关闭服务，便于本地开发
+	stdinShutdown bool
+
+	// shutdownTimeout 是优雅关闭时等待进行中请求完成的最长时间
+	shutdownTimeout time.Duration
+
+	// shutdownHooks 在服务关闭、HTTP服务器停止之后依次执行
+	shutdownHooks []func(context.Context) error
+
+	// accessLog 为true时记录每个请求的方法、路径、状态码和耗时
+	accessLog bool
+
+	// tlsCertFile和tlsKeyFile非空时服务通过HTTPS提供，并以https://地址注册
+	tlsCertFile, tlsKeyFile string
+
+	// watchdogInterval 大于0时，服务定期确认自己仍在注册中心，不在时重新注册
+	watchdogInterval time.Duration
+
+	// groupManaged 为true时服务由Group启动，不单独监听SIGINT/SIGTERM
+	groupManaged bool
+
+	// tlsConfig 由Start根据证书文件加载，startService据此选择ServeTLS
+	tlsConfig *tls.Config
+}
+
+// newOptions 根据传入的Option构造配置，未设置的项使用默认值
+func newOptions(opts []Option) *options {
+	o := &options{
+		maxDecompressedBytes: DefaultMaxDecompressedBytes,
+		shutdownTimeout:      DefaultShutdownTimeout,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMaxConns 设置服务同时接受的最大连接数
+// 服务在高负载下可借此保护自身，避免连接数无限增长耗尽资源
+// 参数:
+// - n: 最大并发连接数，小于等于0表示不限制
+func WithMaxConns(n int) Option {
+	return func(o *options) {
+		o.maxConns = n
+	}
+}
+
+// WithRejectExcessConns 使超出最大连接数的新连接被直接拒绝(关闭)而不是排队
+// 仅在同时设置了WithMaxConns时生效
+func WithRejectExcessConns() Option {
+	return func(o *options) {
+		o.rejectExcessConns = true
+	}
+}
+
+// WithMaxDecompressedBytes 设置gzip压缩请求体解压后的最大字节数
+// 默认值为DefaultMaxDecompressedBytes
+// 参数:
+// - n: 解压后的最大字节数
+func WithMaxDecompressedBytes(n int64) Option {
+	return func(o *options) {
+		o.maxDecompressedBytes = n
+	}
+}
+
+// WithRegistryPreflight 使Start在绑定端口之前先检查注册中心是否可达
+// 注册中心不可达时Start直接返回错误，服务不会开始监听
+func WithRegistryPreflight() Option {
+	return func(o *options) {
+		o.registryPreflight = true
+	}
+}
+
+// WithReusePort 使服务的监听套接字启用SO_REUSEPORT
+// 新进程可以在旧进程仍在监听时绑定同一端口，滚动重启时不会出现
+// "address already in use"错误；两个进程同时监听期间，内核在它们之间分配新连接
+// Go在Unix平台上默认已经设置SO_REUSEADDR，TIME_WAIT状态的端口无需此选项即可重新绑定
+// 仅在Linux、macOS和BSD平台上生效
+func WithReusePort() Option {
+	return func(o *options) {
+		o.reusePort = true
+	}
+}
+
+// WithHealthCheck 设置服务自身的健康检查函数
+// GET /health会调用它，返回错误时响应503，
+// 注册中心的心跳检查据此判断服务是否存活
+// 参数:
+// - health: 健康检查函数，例如检查数据库连接是否可用
+func WithHealthCheck(health func() error) Option {
+	return func(o *options) {
+		o.health = health
+	}
+}
+
+// WithStdinShutdown 使服务在控制台输入回车时关闭，便于本地开发调试
+// 默认只响应SIGINT/SIGTERM；在systemd、Docker等没有交互式stdin的环境中不要启用
+func WithStdinShutdown() Option {
+	return func(o *options) {
+		o.stdinShutdown = true
+	}
+}
+
+// WithShutdownTimeout 设置优雅关闭时等待进行中请求完成的最长时间
+// 超时后剩余连接会被强制关闭，默认值为DefaultShutdownTimeout
+// 参数:
+// - d: 等待时长
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.shutdownTimeout = d
+	}
+}
+
+// WithShutdownHook 注册一个在服务关闭时执行的钩子，可多次调用注册多个
+// 钩子在注销服务、HTTP服务器停止之后按注册顺序执行，
+// 传入的上下文在关闭宽限期结束时到期，适合刷新缓冲区、关闭文件等收尾工作
+// 参数:
+// - hook: 关闭钩子，返回的错误会被记录
+func WithShutdownHook(hook func(ctx context.Context) error) Option {
+	return func(o *options) {
+		o.shutdownHooks = append(o.shutdownHooks, hook)
+	}
+}
+
+// WithAccessLog 为服务的所有请求开启访问日志
+// 每个请求记录一行方法、路径、状态码和耗时；
+// 服务调用log.SetClientLogger之后，访问日志会发送到中央日志服务
+func WithAccessLog() Option {
+	return func(o *options) {
+		o.accessLog = true
+	}
+}
+
+// WithTLS 使服务通过HTTPS提供，注册到注册中心的地址相应改为https://
+// 证书在Start绑定端口之前加载，文件不存在或格式错误时Start直接返回错误；
+// 调用方需要信任该证书时，可使用registry.NewTLSClient加载签发它的CA
+// 参数:
+// - certFile: PEM格式的证书文件路径，可包含中间证书链
+// - keyFile: PEM格式的私钥文件路径
+func WithTLS(certFile, keyFile string) Option {
+	return func(o *options) {
+		o.tlsCertFile = certFile
+		o.tlsKeyFile = keyFile
+	}
+}
+
+// WithReregister 使服务在注册中心丢失其注册信息(例如注册中心重启)后自动重新注册
+// 服务每隔interval通过GET /services确认自己仍在注册表中
+// 参数:
+// - interval: 检查间隔，小于等于0时使用registry.DefaultWatchdogInterval
+func WithReregister(interval time.Duration) Option {
+	return func(o *options) {
+		if interval <= 0 {
+			interval = registry.DefaultWatchdogInterval
+		}
+		o.watchdogInterval = interval
+	}
+}
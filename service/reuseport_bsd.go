@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package service
+
+import (
+	"syscall"
+)
+
+// soReusePort 是SO_REUSEPORT套接字选项
+const soReusePort = syscall.SO_REUSEPORT
@@ -0,0 +1,6 @@
+//go:build linux && !(mips || mipsle || mips64 || mips64le)
+
+package service
+
+// soReusePort 是Linux上SO_REUSEPORT的取值，syscall包没有导出该常量
+const soReusePort = 0xf
@@ -0,0 +1,16 @@
+//go:build !((linux && !(mips || mipsle || mips64 || mips64le)) || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package service
+
+import (
+	"syscall"
+)
+
+// reusePortSupported 表示当前平台支持SO_REUSEPORT
+const reusePortSupported = false
+
+// reusePortControl 在不支持SO_REUSEPORT的平台上不做任何设置
+// Windows的SO_REUSEADDR语义不同(允许抢占正在使用的端口)，因此不启用
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build (linux && !(mips || mipsle || mips64 || mips64le)) || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package service
+
+import (
+	"syscall"
+)
+
+// reusePortSupported 表示当前平台支持SO_REUSEPORT
+const reusePortSupported = true
+
+// reusePortControl 在监听套接字绑定前设置SO_REUSEPORT
+// Go在Unix平台上默认已经设置SO_REUSEADDR，TIME_WAIT不会阻止重新绑定；
+// SO_REUSEPORT进一步允许新进程在旧进程仍在监听时绑定同一端口，实现无缝重启
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	var sockErr error
+	err := c.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
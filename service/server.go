@@ -3,47 +3,140 @@ package service
 import (
 	"My_mimiDistributed/registry"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 )
 
 // Start 函数用于启动微服务
 // 这是一个通用的服务启动函数，适用于系统中的所有微服务
 // 微服务架构设计模式：提取共同的服务启动逻辑，实现代码复用
 // 业务流程:
-// 1. 注册服务的HTTP处理函数
-// 2. 启动HTTP服务器
-// 3. 向注册中心注册服务
-// 4. 返回可控制服务生命周期的上下文
+// 1. (可选)预检注册中心是否可达
+// 2. 注册服务的HTTP处理函数
+// 3. 启动HTTP服务器
+// 4. 向注册中心注册服务
+// 5. 返回可控制服务生命周期的上下文
 // 参数:
 // - ctx: 上下文，用于控制服务生命周期
 // - reg: 服务注册信息，包含服务名称和URL
 // - host: 服务主机名
 // - port: 服务监听端口
-// - registerHandlesFunc: 注册HTTP路由的回调函数
+// - registerHandlesFunc: 在服务专属的路由器上注册HTTP路由的回调函数
+// - opts: 可选配置，如最大并发连接数
 // 返回:
 // - context.Context: 可用于服务生命周期管理的上下文
 // - error: 启动过程中的错误
 func Start(ctx context.Context, reg registry.Registration, host, port string,
-	registerHandlesFunc func()) (context.Context, error) {
+	registerHandlesFunc func(mux *http.ServeMux), opts ...Option) (context.Context, error) {
+	inst, err := start(ctx, reg, host, port, registerHandlesFunc, newOptions(opts))
+	if inst != nil {
+		ctx = inst.ctx
+	}
+	return ctx, err
+}
+
+// instance 是一个已启动的服务
+type instance struct {
+	// name 是服务名称
+	name registry.ServiceName
+
+	// ctx 在服务关闭后被取消
+	ctx context.Context
+
+	// watchCtx 是注册看门狗的上下文，在注销之前就被取消，
+	// 看门狗不会把正在关闭的服务重新注册回去
+	watchCtx context.Context
+
+	// shutdown 注销并优雅关闭服务，完成后才返回，重复调用只执行一次
+	// 传入的上下文到期时，剩余的等待被放弃
+	shutdown func(ctx context.Context)
+}
+
+// start 是Start的实现，返回的instance供Group统一关闭服务
+// HTTP服务器已经启动但注册失败时，同时返回instance和错误
+func start(ctx context.Context, reg registry.Registration, host, port string,
+	registerHandlesFunc func(mux *http.ServeMux), o *options) (*instance, error) {
+	// 启用预检时，先确认注册中心可达再绑定端口
+	// 避免服务已经开始接收流量却无法完成注册
+	if o.registryPreflight {
+		if err := registry.Ping(); err != nil {
+			return nil, err
+		}
+	}
+
+	// 每个服务使用自己的路由器，同一进程中的多个服务不会发生路由冲突
+	mux := http.NewServeMux()
+
 	// 调用传入的函数注册HTTP路由处理器
 	// 这是依赖注入和控制反转的示例，服务框架不需要知道具体的HTTP处理逻辑
-	registerHandlesFunc()
+	registerHandlesFunc(mux)
+
+	// 注册所有服务通用的健康检查接口
+	registerHealthHandlers(mux, reg, o.health)
+
+	// 注册请求计数器接口
+	registerMetricsHandler(mux)
+
+	// 注册构建信息接口
+	registerVersionHandler(mux, reg.ServiceName)
+
+	// 注册接收依赖更新通知的处理器
+	if err := registry.RegisterUpdateHandler(mux, reg); err != nil {
+		return nil, err
+	}
+
+	// 启用TLS时先加载证书，证书有误时不绑定端口；注册的地址改为https://
+	if o.tlsCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.tlsCertFile, o.tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		o.tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		reg.ServiceURL = withScheme(reg.ServiceURL, "https")
+		reg.ServiceUpdateURL = withScheme(reg.ServiceUpdateURL, "https")
+	}
+
+	// 先绑定端口再注册，服务注册时已经能够接收请求
+	// 端口为"0"时由系统分配空闲端口，注册的URL使用实际绑定的端口
+	ln, err := listen(":"+port, o)
+	if err != nil {
+		return nil, err
+	}
+	if bound := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port); bound != port {
+		reg.ServiceURL = withPort(reg.ServiceURL, bound)
+		reg.ServiceUpdateURL = withPort(reg.ServiceUpdateURL, bound)
+		port = bound
+		log.Printf("%v bound to port %v, registering as %v", reg.ServiceName, port, reg.ServiceURL)
+	}
 
 	// 启动HTTP服务器，返回包含取消功能的上下文
-	// 这一步使服务开始监听指定端口，准备接收请求
-	ctx = startService(ctx, reg.ServiceName, host, port)
+	// 这一步使服务开始在监听器上接收请求
+	inst := startService(ctx, mux, ln, reg.ServiceName, reg.ServiceURL, port, o)
 
 	// 向注册中心注册当前服务
 	// 这样其他服务就能发现并使用此服务
 	// 注册过程还会使当前服务获得它所依赖的服务信息
-	err := registry.RegisterService(reg)
+	err = registry.RegisterServiceContext(inst.ctx, reg)
 	if err != nil {
-		return ctx, err
+		return inst, err
+	}
+
+	// 启用看门狗时，注册中心丢失注册信息后自动重新注册
+	if o.watchdogInterval > 0 {
+		go registry.WatchRegistration(inst.watchCtx, reg, o.watchdogInterval)
 	}
 
-	return ctx, nil
+	return inst, nil
 }
 
 // startService 启动HTTP服务器并设置优雅关闭机制
@@ -52,20 +145,28 @@ func Start(ctx context.Context, reg registry.Registration, host, port string,
 // 业务流程:
 // 1. 创建可取消的上下文
 // 2. 配置并启动HTTP服务器
-// 3. 设置用户控制和监控机制
+// 3. 监听SIGINT/SIGTERM(以及可选的控制台输入)，收到后优雅关闭；由Group启动时由Group负责关闭
 // 4. 设置服务关闭时的自动注销
 // 参数:
 // - ctx: 父上下文
+// - mux: 服务的路由器
+// - ln: 已绑定端口的监听器
 // - serviceName: 服务名称，用于日志和提示
-// - host: 服务主机名
+// - serviceURL: 实际注册的服务URL(已包含TLS和端口的改写)，注销时使用
 // - port: 服务监听端口
+// - opts: 服务启动的可选配置
 // 返回:
-// - context.Context: 带取消功能的派生上下文
-func startService(ctx context.Context, serviceName registry.ServiceName, host, port string) context.Context {
+// - *instance: 已启动的服务，其上下文在服务关闭后被取消
+func startService(ctx context.Context, mux *http.ServeMux, ln net.Listener,
+	serviceName registry.ServiceName, serviceURL, port string,
+	opts *options) *instance {
 	// 创建一个可取消的上下文，派生自传入的上下文
 	// 这使得服务可以被外部信号或内部错误优雅地终止
 	ctx, cancel := context.WithCancel(ctx)
 
+	// 看门狗使用单独的上下文，注销前先停止它
+	watchCtx, stopWatch := context.WithCancel(ctx)
+
 	// 创建HTTP服务器实例
 	// Go标准库提供的http.Server包含丰富的配置选项
 	var srv http.Server
@@ -74,41 +175,153 @@ func startService(ctx context.Context, serviceName registry.ServiceName, host, p
 	// 例如端口4000则为:4000
 	srv.Addr = ":" + port
 
+	// 透明解压gzip压缩的请求体，适用于批量导入、批量日志等大请求
+	// 同时统计每个请求的路由和状态码，供GET /metrics输出
+	srv.Handler = countRequests(gzipRequestBody(mux, opts.maxDecompressedBytes))
+	if opts.accessLog {
+		srv.Handler = accessLog(srv.Handler)
+	}
+
+	// 配置了证书时通过HTTPS提供服务
+	srv.TLSConfig = opts.tlsConfig
+
+	// 注销只执行一次，无论关闭由信号、控制台输入还是服务器出错触发
+	// 使用注册时的URL注销，而不是由主机名和端口重新拼接，保证与注册表中的条目一致
+	var deregisterOnce sync.Once
+	deregister := func() {
+		deregisterOnce.Do(func() {
+			// 先停止看门狗，否则它可能在注销之后发现注册丢失并重新注册
+			stopWatch()
+			// 向注册中心注销服务，确保注册中心维护的服务列表是最新的
+			if err := registry.ShutdownService(serviceURL); err != nil {
+				log.Println(err)
+			}
+		})
+	}
+
+	// runHooks 依次执行服务注册的关闭钩子，例如刷新缓冲区、关闭文件
+	runHooks := func(ctx context.Context) {
+		for _, hook := range opts.shutdownHooks {
+			if err := hook(ctx); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	// shutdown 先注销服务，再优雅关闭HTTP服务器，最后执行关闭钩子
+	// Shutdown会在宽限期内等待所有活跃连接完成，宽限期派生自parent而不是服务上下文，
+	// 避免服务上下文已被取消时进行中的请求被直接丢弃；Group借parent为多个服务设置共同的期限
+	var shutdownOnce sync.Once
+	shutdown := func(parent context.Context) {
+		shutdownOnce.Do(func() {
+			deregister()
+			shutdownCtx, stop := context.WithTimeout(parent, opts.shutdownTimeout)
+			defer stop()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("%v shutdown timed out after %v: %v", serviceName, opts.shutdownTimeout, err)
+				srv.Close()
+			} else {
+				log.Printf("%v shut down cleanly", serviceName)
+			}
+			runHooks(shutdownCtx)
+			cancel()
+		})
+	}
+
 	// 启动一个goroutine运行HTTP服务器
 	// 使用goroutine避免阻塞主流程
-	// 当服务器关闭或出错时，会调用cancel()
+	// 服务器因出错而停止时，注销服务、执行关闭钩子并调用cancel()；
+	// 由shutdown触发的正常关闭由shutdown自己完成这些步骤
 	go func() {
-		log.Println(srv.ListenAndServe())
-		// 当服务器关闭时，向注册中心注销服务
-		// 这确保注册中心维护的服务列表是最新的
-		err := registry.ShutdownService(fmt.Sprintf("http://%s:%s", host, port))
-		if err != nil {
-			log.Println(err)
+		var err error
+		if srv.TLSConfig != nil {
+			// 证书已在TLSConfig中，无需再传文件路径
+			err = srv.ServeTLS(ln, "", "")
+		} else {
+			err = srv.Serve(ln)
 		}
+		if errors.Is(err, http.ErrServerClosed) {
+			return
+		}
+		log.Println(err)
+		deregister()
+		hookCtx, stop := context.WithTimeout(context.Background(), opts.shutdownTimeout)
+		defer stop()
+		runHooks(hookCtx)
 		cancel()
 	}()
 
-	// 启动一个goroutine监听用户输入，实现优雅关闭
-	// 这提供了一种通过控制台手动关闭服务的方式
-	go func() {
-		fmt.Printf(" %v start ,press any key to stop service \n", serviceName)
-		var s string
-		// 阻塞等待用户输入
-		fmt.Scanln(&s)
+	// 监听SIGINT和SIGTERM，在systemd、Docker、Kubernetes等环境中实现优雅关闭
+	// 由Group启动的服务不单独监听信号，统一由Group按顺序关闭
+	if !opts.groupManaged {
+		go func() {
+			sigs := make(chan os.Signal, 1)
+			signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+			defer signal.Stop(sigs)
+			select {
+			case <-sigs:
+				shutdown(context.Background())
+			case <-ctx.Done():
+			}
+		}()
+	}
 
-		// 用户输入后，向注册中心注销服务
-		err := registry.ShutdownService(fmt.Sprintf("http://%s:%s", host, port))
-		if err != nil {
-			log.Println(err)
-		}
+	// 本地开发时可通过控制台输入关闭服务
+	if opts.stdinShutdown {
+		go func() {
+			fmt.Printf(" %v start ,press any key to stop service \n", serviceName)
+			var s string
+			// 阻塞等待用户输入
+			fmt.Scanln(&s)
+			shutdown(context.Background())
+		}()
+	} else {
+		fmt.Printf(" %v start ,press Ctrl+C to stop service \n", serviceName)
+	}
 
-		// 优雅关闭HTTP服务器
-		// Shutdown会等待所有活跃连接完成后再关闭
-		srv.Shutdown(ctx)
+	return &instance{name: serviceName, ctx: ctx, watchCtx: watchCtx, shutdown: shutdown}
+}
 
-		// 取消上下文，通知所有监听此上下文的goroutine
-		cancel()
-	}()
+// listen 创建服务的监听器
+// 启用端口复用时设置SO_REUSEPORT，
+// 配置了最大连接数时，使用limitListener包装底层监听器
+// 参数:
+// - addr: 监听地址，例如":4000"，端口为0时由系统分配
+// - opts: 服务启动的可选配置
+// 返回:
+// - net.Listener: 已绑定端口的监听器
+// - error: 绑定端口失败的原因
+func listen(addr string, opts *options) (net.Listener, error) {
+	var lc net.ListenConfig
+	if opts.reusePort {
+		lc.Control = reusePortControl
+	}
+	ln, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if opts.maxConns > 0 {
+		ln = newLimitListener(ln, opts.maxConns, opts.rejectExcessConns)
+	}
+	return ln, nil
+}
 
-	return ctx
+// withPort 把rawURL中的端口替换为port，无法解析时原样返回
+func withPort(rawURL, port string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), port)
+	return u.String()
+}
+
+// withScheme 把rawURL的协议替换为scheme，无法解析时原样返回
+func withScheme(rawURL, scheme string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = scheme
+	return u.String()
 }
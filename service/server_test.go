@@ -0,0 +1,366 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWatchdogStopsBeforeDeregister(t *testing.T) {
+	startRegistry(t)
+
+	// 关闭钩子执行期间服务已经注销，但服务上下文尚未取消，
+	// 看门狗若仍在运行就会把服务重新注册回去
+	slowHook := WithShutdownHook(func(context.Context) error {
+		time.Sleep(100 * time.Millisecond)
+		return nil
+	})
+
+	g := NewGroup(5 * time.Second)
+	_, err := g.Start(registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+	}, "localhost", "0", func(*http.ServeMux) {}, WithReregister(5*time.Millisecond), slowHook)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.Shutdown()
+	time.Sleep(50 * time.Millisecond)
+	if names := registeredNames(t); len(names) != 0 {
+		t.Fatalf("services re-registered after shutdown: %v", names)
+	}
+}
+
+func TestReusePortAllowsSecondListener(t *testing.T) {
+	if !reusePortSupported {
+		t.Skip("SO_REUSEPORT is not supported on this platform")
+	}
+	opts := &options{reusePort: true}
+	first, err := listen(":0", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+
+	// 旧监听器仍在工作时，新进程应能绑定同一端口
+	port := strconv.Itoa(first.Addr().(*net.TCPAddr).Port)
+	second, err := listen(":"+port, opts)
+	if err != nil {
+		t.Fatalf("rebinding port %v with reuse enabled: %v", port, err)
+	}
+	second.Close()
+
+	if ln, err := listen(":"+port, &options{}); err == nil {
+		ln.Close()
+		t.Fatalf("rebinding port %v without reuse succeeded", port)
+	}
+}
+
+func TestRegisteredServiceAcceptsConnectionsImmediately(t *testing.T) {
+	startRegistry(t)
+
+	// 依赖方收到新增通知后立刻连接新服务，不做任何重试
+	connected := make(chan error, 1)
+	dependent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p struct{ Added []struct{ URL string } }
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil || len(p.Added) == 0 {
+			return
+		}
+		res, err := http.Get(p.Added[0].URL + "/health")
+		if err == nil {
+			res.Body.Close()
+		}
+		connected <- err
+	}))
+	defer dependent.Close()
+	err := registry.RegisterService(registry.Registration{
+		ServiceName:      registry.GradingService,
+		ServiceURL:       dependent.URL,
+		ServiceUpdateURL: dependent.URL,
+		RequireServices:  []registry.ServiceName{registry.LogService},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.DeregisterService(dependent.URL, registry.ReasonShutdown)
+
+	g := NewGroup(5 * time.Second)
+	defer g.Shutdown()
+	_, err = g.Start(registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+	}, "localhost", "0", func(*http.ServeMux) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-connected:
+		if err != nil {
+			t.Fatalf("connecting right after registration failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dependent was never notified of the new service")
+	}
+}
+
+func TestShutdownDeregistersAdvertisedURL(t *testing.T) {
+	startRegistry(t)
+
+	// 注册的URL与host参数不同，端口也由系统分配后改写
+	g := NewGroup(5 * time.Second)
+	_, err := g.Start(registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://127.0.0.1:0",
+		ServiceUpdateURL: "http://127.0.0.1:0/services",
+	}, "localhost", "0", func(*http.ServeMux) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := registeredNames(t); len(names) != 1 {
+		t.Fatalf("registered services = %v, want only the log service", names)
+	}
+
+	g.Shutdown()
+	if names := registeredNames(t); len(names) != 0 {
+		t.Fatalf("services still registered after shutdown: %v", names)
+	}
+}
+
+func TestPreflightFailsBeforeBindingWhenRegistryDown(t *testing.T) {
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	registry.SetRegistryURL(down.URL)
+	t.Cleanup(func() { registry.SetRegistryURL("") })
+
+	// 取得一个空闲端口，Start失败后该端口应仍然空闲
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	ln.Close()
+
+	handlersRegistered := false
+	_, err = Start(context.Background(), registry.Registration{
+		ServiceName: registry.LogService,
+		ServiceURL:  "http://localhost:" + port,
+	}, "localhost", port, func(*http.ServeMux) { handlersRegistered = true }, WithRegistryPreflight())
+	if err == nil || !strings.Contains(err.Error(), "registry unreachable") {
+		t.Fatalf("Start with a down registry = %v, want a registry unreachable error", err)
+	}
+	if handlersRegistered {
+		t.Error("Start set up handlers before the preflight check")
+	}
+
+	ln, err = net.Listen("tcp", "localhost:"+port)
+	if err != nil {
+		t.Fatalf("port %v was bound despite the failed preflight: %v", port, err)
+	}
+	ln.Close()
+}
+
+func TestSIGTERMDeregistersService(t *testing.T) {
+	startRegistry(t)
+
+	// 测试自己也监听SIGTERM，信号在服务开始监听之前到达时不会终止测试进程
+	guard := make(chan os.Signal, 16)
+	signal.Notify(guard, syscall.SIGTERM)
+	defer signal.Stop(guard)
+
+	ctx, err := Start(context.Background(), registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+	}, "localhost", "0", func(*http.ServeMux) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := registeredNames(t); len(names) != 1 {
+		t.Fatalf("registered services = %v, want the log service", names)
+	}
+
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 服务在单独的goroutine中开始监听信号，重复发送直到服务关闭
+	deadline := time.After(5 * time.Second)
+	for ctx.Err() == nil {
+		if err := self.Signal(syscall.SIGTERM); err != nil {
+			t.Skipf("cannot signal the test process: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(20 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("service did not shut down on SIGTERM")
+		}
+	}
+
+	if names := registeredNames(t); len(names) != 0 {
+		t.Fatalf("services still registered after SIGTERM: %v", names)
+	}
+}
+
+// startSlowService 启动一个处理请求需要delay的服务，返回服务实例和它注册的URL
+func startSlowService(t *testing.T, delay, grace time.Duration) (*instance, string, chan struct{}) {
+	t.Helper()
+	startRegistry(t)
+	entered := make(chan struct{}, 1)
+	inst, err := start(context.Background(), registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+	}, "localhost", "0", func(mux *http.ServeMux) {
+		mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+			entered <- struct{}{}
+			time.Sleep(delay)
+			w.Write([]byte("done"))
+		})
+	}, newOptions([]Option{WithShutdownTimeout(grace)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	regs, err := registry.ListRegistrations(context.Background())
+	if err != nil || len(regs) != 1 {
+		t.Fatalf("registrations = %v, %v; want the slow service", regs, err)
+	}
+	return inst, regs[0].ServiceURL, entered
+}
+
+func TestShutdownLetsInFlightRequestFinish(t *testing.T) {
+	inst, url, entered := startSlowService(t, 200*time.Millisecond, 5*time.Second)
+
+	result := make(chan error, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		if err == nil {
+			defer res.Body.Close()
+			if body, _ := io.ReadAll(res.Body); string(body) != "done" {
+				err = fmt.Errorf("response body %q, want done", body)
+			}
+		}
+		result <- err
+	}()
+	<-entered
+	inst.shutdown(context.Background())
+
+	if err := <-result; err != nil {
+		t.Fatalf("in-flight request during graceful shutdown failed: %v", err)
+	}
+}
+
+func TestShutdownCutsOffRequestsAfterGracePeriod(t *testing.T) {
+	inst, url, entered := startSlowService(t, 2*time.Second, 50*time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() {
+		res, err := http.Get(url + "/slow")
+		if err == nil {
+			res.Body.Close()
+		}
+		result <- err
+	}()
+	<-entered
+	start := time.Now()
+	inst.shutdown(context.Background())
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %v, want it bounded by the 50ms grace period", elapsed)
+	}
+	if err := <-result; err == nil {
+		t.Error("request outliving the grace period succeeded, want the connection closed")
+	}
+}
+
+func TestTwoServicesRegisterSameRouteInOneProcess(t *testing.T) {
+	startRegistry(t)
+	g := NewGroup(5 * time.Second)
+	defer g.Shutdown()
+
+	// 两个服务注册相同的路由，使用全局路由器时第二次注册会panic
+	for _, name := range []registry.ServiceName{registry.LogService, registry.GradingService} {
+		_, err := g.Start(registry.Registration{
+			ServiceName:      name,
+			ServiceURL:       "http://localhost:0",
+			ServiceUpdateURL: "http://localhost:0/services",
+		}, "localhost", "0", func(mux *http.ServeMux) {
+			mux.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, name)
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	regs, err := registry.ListRegistrations(context.Background())
+	if err != nil || len(regs) != 2 {
+		t.Fatalf("registrations = %v, %v; want both services", regs, err)
+	}
+	for _, reg := range regs {
+		res, err := http.Get(reg.ServiceURL + "/whoami")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != string(reg.ServiceName) {
+			t.Errorf("%v answered /whoami with %q, want its own handler", reg.ServiceName, body)
+		}
+	}
+}
+
+func TestPortZeroRegistersBoundPort(t *testing.T) {
+	startRegistry(t)
+	g := NewGroup(5 * time.Second)
+	defer g.Shutdown()
+	_, err := g.Start(registry.Registration{
+		ServiceName:      registry.LogService,
+		ServiceURL:       "http://localhost:0",
+		ServiceUpdateURL: "http://localhost:0/services",
+	}, "localhost", "0", func(*http.ServeMux) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	regs, err := registry.ListRegistrations(context.Background())
+	if err != nil || len(regs) != 1 {
+		t.Fatalf("registrations = %v, %v; want the log service", regs, err)
+	}
+	u, err := url.Parse(regs[0].ServiceURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil || port == 0 {
+		t.Fatalf("registered URL %v does not carry the bound port", regs[0].ServiceURL)
+	}
+	if want := regs[0].ServiceURL + "/services"; regs[0].ServiceUpdateURL != want {
+		t.Errorf("update URL = %v, want %v", regs[0].ServiceUpdateURL, want)
+	}
+
+	// 注册的地址确实可以访问
+	res, err := http.Get(regs[0].ServiceURL + "/health")
+	if err != nil {
+		t.Fatalf("registered URL is not reachable: %v", err)
+	}
+	res.Body.Close()
+}
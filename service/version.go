@@ -0,0 +1,82 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// BuildInfo 是GET /version返回的构建信息
+type BuildInfo struct {
+	Service   registry.ServiceName
+	Version   string
+	Commit    string `json:",omitempty"`
+	GoVersion string
+}
+
+// buildVersion和buildCommit由SetBuildInfo设置
+// 未设置时版本为"dev"，提交取自go build嵌入的VCS信息(如果有)
+var (
+	buildVersion = "dev"
+	buildCommit  = vcsRevision()
+	buildMu      sync.RWMutex
+)
+
+// SetBuildInfo 设置GET /version返回的版本号和git提交
+// 通常在main函数开头调用，版本号可在构建时通过-ldflags -X注入main包的变量再传入
+// 参数:
+// - version: 版本号，例如v1.2.0，为空时保持原值
+// - commit: git提交哈希，为空时保持原值
+func SetBuildInfo(version, commit string) {
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	if version != "" {
+		buildVersion = version
+	}
+	if commit != "" {
+		buildCommit = commit
+	}
+}
+
+// vcsRevision 返回go build嵌入的git提交哈希，没有时返回空字符串
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
+
+// registerVersionHandler 在mux上注册GET /version，返回服务的构建信息
+// 参数:
+// - mux: 服务的路由器
+// - name: 服务名称
+func registerVersionHandler(mux *http.ServeMux, name registry.ServiceName) {
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		buildMu.RLock()
+		info := BuildInfo{
+			Service:   name,
+			Version:   buildVersion,
+			Commit:    buildCommit,
+			GoVersion: runtime.Version(),
+		}
+		buildMu.RUnlock()
+		data, err := json.Marshal(info)
+		if err != nil {
+			log.Println(err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
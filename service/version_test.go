@@ -0,0 +1,60 @@
+package service
+
+import (
+	"My_mimiDistributed/registry"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+// withBuildInfo 在测试期间设置构建信息，测试结束后恢复
+func withBuildInfo(t *testing.T, version, commit string) {
+	t.Helper()
+	buildMu.RLock()
+	savedVersion, savedCommit := buildVersion, buildCommit
+	buildMu.RUnlock()
+	t.Cleanup(func() {
+		buildMu.Lock()
+		buildVersion, buildCommit = savedVersion, savedCommit
+		buildMu.Unlock()
+	})
+	SetBuildInfo(version, commit)
+}
+
+// getVersion 请求GET /version并解码返回的构建信息
+func getVersion(t *testing.T, name registry.ServiceName) BuildInfo {
+	t.Helper()
+	mux := http.NewServeMux()
+	registerVersionHandler(mux, name)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %v, want 200", rec.Code)
+	}
+	var info BuildInfo
+	if err := json.NewDecoder(rec.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestVersionReturnsConfiguredBuildInfo(t *testing.T) {
+	withBuildInfo(t, "v1.2.0", "abc123")
+
+	want := BuildInfo{Service: registry.GradingService, Version: "v1.2.0", Commit: "abc123",
+		GoVersion: runtime.Version()}
+	if got := getVersion(t, registry.GradingService); got != want {
+		t.Errorf("GET /version = %+v, want %+v", got, want)
+	}
+}
+
+func TestSetBuildInfoKeepsValuesForEmptyArguments(t *testing.T) {
+	withBuildInfo(t, "v1.2.0", "abc123")
+	SetBuildInfo("", "")
+
+	if got := getVersion(t, registry.LogService); got.Version != "v1.2.0" || got.Commit != "abc123" {
+		t.Errorf("GET /version = %+v, want the earlier version and commit", got)
+	}
+}